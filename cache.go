@@ -17,80 +17,376 @@ limitations under the License.
 package metaser
 
 import (
+	"encoding"
+	"fmt"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 type fieldInfo struct {
 	path []int
 	tag  parsedTag
+	// fallback is 1-based index of tag fallback the item is indexed by or 0 for primary key.
+	fallback int
+	// index is position of the field in Fields. Items indexed by different keys of the same field share it.
+	index int
 }
 
-type cache struct {
-	CachedType             reflect.Type
-	NameFastAccess         []fieldInfo
-	NamespaceFastAccess    []fieldInfo
-	AnnotationFastAccess   map[string][]fieldInfo
-	LabelsFastAccess       map[string][]fieldInfo
-	CustomFieldsFastAccess []fieldInfo
+// typeCache contains fast access indexes of tagged fields of single type.
+type typeCache struct {
+	CachedType              reflect.Type
+	NameFastAccess          []fieldInfo
+	NamespaceFastAccess     []fieldInfo
+	AnnotationFastAccess    map[string][]fieldInfo
+	LabelsFastAccess        map[string][]fieldInfo
+	CustomFieldsFastAccess  []fieldInfo
+	OwnerFastAccess         []fieldInfo
+	LabelPresenceFastAccess []fieldInfo
+	ProviderFastAccess      []fieldInfo
+	TimestampFastAccess     []fieldInfo
+	FinalizersFastAccess    []fieldInfo
+	GenerationFastAccess    []fieldInfo
+	DefaultFastAccess       []fieldInfo
+	WholeMapFastAccess      []fieldInfo
+	// Fields contains all tagged fields in order of registration.
+	Fields []fieldInfo
+	// Flat is true if all fields are top-level primitives stored in annotations or labels under single key.
+	// Such types are decoded by looking up keys of fields instead of walking metadata.
+	Flat bool
 }
 
-func newCache(root reflect.Type) (*cache, error) {
+// newTypeCache builds cache of root type. hasCodec reports types with registered codecs, it may be nil.
+func newTypeCache(root reflect.Type, hasCodec func(reflect.Type) bool) (*typeCache, error) {
 
-	c := &cache{}
+	c := &typeCache{}
 	c.AnnotationFastAccess = map[string][]fieldInfo{}
 	c.LabelsFastAccess = map[string][]fieldInfo{}
 	c.CustomFieldsFastAccess = nil
 	c.NameFastAccess = nil
 	c.NamespaceFastAccess = nil
 
-	err := visit(root, func(t reflect.Type, path []int) (bool, error) {
-		if t.Kind() == reflect.Pointer {
-			return true, nil
+	// root type is set before build, so it can name fields in errors
+	c.CachedType = root
+	err := c.build(root, nil, "", nil, hasCodec)
+	if err != nil {
+		c.CachedType = nil
+		return c, err
+	}
+	c.Flat = isFlat(root, c.Fields)
+	return c, nil
+}
+
+// isFlat checks if all fields of root are top-level primitives with default encoding stored in annotations or labels
+// without aliases, alternative sources, defaults or ranges.
+func isFlat(root reflect.Type, fields []fieldInfo) bool {
+	for root.Kind() == reflect.Pointer {
+		root = root.Elem()
+	}
+	for i := range fields {
+		info := &fields[i]
+		pt := &info.tag
+		if len(info.path) != 1 || (pt.source != annotation && pt.source != label) || pt.inline || pt.isRange || pt.hasDefault ||
+			pt.enc != encoder(undefined) || len(pt.aliases) > 0 || len(pt.fallbacks) > 0 {
+			return false
 		}
-		if t.Kind() != reflect.Struct {
-			return false, nil
+		t := root.Field(info.path[0]).Type
+		if t.Implements(textUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+			return false
 		}
-		recurse := false
-		for i := 0; i < t.NumField(); i++ {
-			pt, err := parseTag(t.Field(i).Tag)
-			if err != nil {
-				return false, err
-			}
-			if pt == nil {
-				continue
+		switch t.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64, reflect.String:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// build registers tagged fields of struct t (and of its nested structs) located at path. Annotation and label keys
+// are prefixed with prefix accumulated from inline fields. ancestors are used to break reference cycles. hasCodec
+// reports types with registered codecs.
+func (c *typeCache) build(t reflect.Type, path []int, prefix string, ancestors []reflect.Type,
+	hasCodec func(reflect.Type) bool) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// metadata embedded in k8s objects never contains tagged fields, so it is not walked
+	if t.Kind() != reflect.Struct || t == objectMetaType || t == typeMetaType || slices.Contains(ancestors, t) {
+		return nil
+	}
+	ancestors = append(ancestors, t)
+
+	type child struct {
+		t      reflect.Type
+		path   []int
+		prefix string
+		field  reflect.StructField
+	}
+	children := make([]child, 0, t.NumField())
+	recurse := false
+	for i := 0; i < t.NumField(); i++ {
+		// path is copied, so paths of sibling fields never share backing array
+		p := make([]int, len(path)+1)
+		copy(p, path)
+		p[len(path)] = i
+		pt, err := parseTag(t.Field(i).Tag)
+		if err != nil {
+			return err
+		}
+		if pt == nil {
+			children = append(children, child{t.Field(i).Type, p, prefix, t.Field(i)})
+			continue
+		}
+		if !t.Field(i).IsExported() {
+			return fmt.Errorf("field '%s' of type '%s': unexported fields cannot be tagged", t.Field(i).Name, t)
+		}
+		if u := unsupportedKind(t.Field(i).Type); u != nil && pt.enc != custom && !pt.inline {
+			return &UnsupportedTypeError{Type: u, Field: fieldName(c.CachedType, p)}
+		}
+		if err = checkTextSymmetry(t.Field(i).Type, pt, hasCodec); err != nil {
+			return fmt.Errorf("field '%s': %w", t.Field(i).Name, err)
+		}
+		if err = checkFold(t.Field(i).Type, pt); err != nil {
+			return fmt.Errorf("field '%s': %w", t.Field(i).Name, err)
+		}
+		if err = checkFinalizers(t.Field(i).Type, pt); err != nil {
+			return fmt.Errorf("field '%s': %w", t.Field(i).Name, err)
+		}
+		if err = markRange(t.Field(i).Type, pt); err != nil {
+			return fmt.Errorf("field '%s': %w", t.Field(i).Name, err)
+		}
+		if pt.isRange && pt.hasDefault {
+			return fmt.Errorf("field '%s': 'default' cannot be used with Range", t.Field(i).Name)
+		}
+		if pt.source == source(undefined) && pt.enc == custom {
+			// elements of custom-encoded slices are stored under keys scoped by field path
+			pt.value = fieldName(c.CachedType, p)
+		}
+		recurse = true
+		c.register(fieldInfo{path: p, tag: *pt.withPrefix(prefix)})
+		children = append(children, child{t.Field(i).Type, p, prefix + pt.prefix, t.Field(i)})
+	}
+	if !recurse {
+		return nil
+	}
+	for _, ch := range children {
+		registered := len(c.Fields)
+		if err := c.build(ch.t, ch.path, ch.prefix, ancestors, hasCodec); err != nil {
+			return err
+		}
+		// fields of embedded structs are promoted, so they can be set even if the struct is unexported
+		if !ch.field.IsExported() && !ch.field.Anonymous && len(c.Fields) > registered {
+			return fmt.Errorf("field '%s' of type '%s': unexported fields cannot contain tagged fields", ch.field.Name, t)
+		}
+	}
+	return nil
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unsupportedKind returns type reachable from t through pointers, options and elements of collections, which kind
+// (chan, func or unsafe.Pointer) can never be encoded or decoded. It returns nil if there is no such type.
+func unsupportedKind(t reflect.Type) reflect.Type {
+	for {
+		if t.Implements(textMarshalerType) || t.Implements(textUnmarshalerType) ||
+			reflect.PointerTo(t).Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+			return nil
+		}
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+			return t
+		case reflect.Pointer, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		case reflect.Map:
+			if u := unsupportedKind(t.Key()); u != nil {
+				return u
 			}
-			recurse = true
-			item := fieldInfo{append(path, i), *pt}
-			switch pt.source {
-			case name:
-				c.NameFastAccess = append(c.NameFastAccess, item)
-			case namespace:
-				c.NamespaceFastAccess = append(c.NamespaceFastAccess, item)
-			case annotation:
-				v := c.AnnotationFastAccess[pt.value]
-				v = append(v, item)
-				c.AnnotationFastAccess[pt.value] = v
-				for _, alias := range pt.aliases {
-					c.AnnotationFastAccess[alias] = v
-				}
-			case label:
-				v := c.LabelsFastAccess[pt.value]
-				v = append(v, item)
-				c.LabelsFastAccess[pt.value] = v
-				for _, alias := range pt.aliases {
-					c.LabelsFastAccess[alias] = v
-				}
-			case source(undefined):
-				if pt.enc == custom {
-					c.CustomFieldsFastAccess = append(c.CustomFieldsFastAccess, item)
-				}
+			t = t.Elem()
+		case reflect.Struct:
+			if !strings.HasPrefix(t.String(), "metaser.Option") {
+				return nil
 			}
-			recurse = recurse || pt.inline
+			t = t.Field(valueFieldIndex).Type
+		default:
+			return nil
 		}
-		return recurse, nil
+	}
+}
+
+// checkTextSymmetry verifies if field of type t encoded with encoding.TextMarshaler/encoding.TextUnmarshaler
+// implements interfaces required by its direction. Types with registered codecs are not checked, because codecs
+// take precedence over these interfaces.
+func checkTextSymmetry(t reflect.Type, pt *parsedTag, hasCodec func(reflect.Type) bool) error {
+	if pt.enc != encoder(undefined) || (pt.source != annotation && pt.source != label) {
+		return nil
+	}
+	for {
+		if hasCodec != nil && hasCodec(t) {
+			return nil
+		}
+		if t.Kind() != reflect.Pointer {
+			break
+		}
+		t = t.Elem()
+	}
+	pointer := reflect.PointerTo(t)
+	marshaler := t.Implements(textMarshalerType) || pointer.Implements(textMarshalerType)
+	unmarshaler := t.Implements(textUnmarshalerType) || pointer.Implements(textUnmarshalerType)
+	if marshaler == unmarshaler {
+		return nil
+	}
+	if pt.dir != in && !marshaler {
+		return fmt.Errorf("type '%s' implements encoding.TextUnmarshaler but not encoding.TextMarshaler required for encoding. Use 'in' tag for decode-only fields", t)
+	}
+	if pt.dir != out && !unmarshaler {
+		return fmt.Errorf("type '%s' implements encoding.TextMarshaler but not encoding.TextUnmarshaler required for decoding. Use 'out' tag for encode-only fields", t)
+	}
+	return nil
+}
+
+// checkFold verifies if field tagged with 'fold' is of string kind or pointer or Option of string kind.
+func checkFold(t reflect.Type, pt *parsedTag) error {
+	if !pt.fold {
+		return nil
+	}
+	if t = valueType(t); t.Kind() != reflect.String {
+		return fmt.Errorf("'fold' requires string kind, got '%s'", t)
+	}
+	return nil
+}
+
+func (c *typeCache) register(item fieldInfo) {
+	pt := &item.tag
+	item.index = len(c.Fields)
+	c.Fields = append(c.Fields, item)
+	switch pt.source {
+	case name:
+		c.NameFastAccess = append(c.NameFastAccess, item)
+	case namespace:
+		c.NamespaceFastAccess = append(c.NamespaceFastAccess, item)
+	case owner:
+		c.OwnerFastAccess = append(c.OwnerFastAccess, item)
+	case labelPresence:
+		c.LabelPresenceFastAccess = append(c.LabelPresenceFastAccess, item)
+	case provided:
+		c.ProviderFastAccess = append(c.ProviderFastAccess, item)
+	case creationTimestamp, deletionTimestamp:
+		c.TimestampFastAccess = append(c.TimestampFastAccess, item)
+	case finalizers:
+		c.FinalizersFastAccess = append(c.FinalizersFastAccess, item)
+	case generation:
+		c.GenerationFastAccess = append(c.GenerationFastAccess, item)
+	case allLabels, allAnnotations:
+		c.WholeMapFastAccess = append(c.WholeMapFastAccess, item)
+	case annotation, label:
+		if pt.hasDefault {
+			c.DefaultFastAccess = append(c.DefaultFastAccess, item)
+		}
+		keys := c.AnnotationFastAccess
+		if pt.source == label {
+			keys = c.LabelsFastAccess
+		}
+		if pt.isRange {
+			minKey, maxKey := rangeKeys(pt.value)
+			keys[minKey] = append(keys[minKey], item)
+			keys[maxKey] = append(keys[maxKey], item)
+			break
+		}
+		v := keys[pt.value]
+		v = append(v, item)
+		keys[pt.value] = v
+		for _, alias := range pt.aliases {
+			keys[alias] = v
+		}
+	case source(undefined):
+		if pt.enc == custom {
+			c.CustomFieldsFastAccess = append(c.CustomFieldsFastAccess, item)
+		}
+	}
+	for j, ref := range pt.fallbacks {
+		fallback := fieldInfo{path: item.path, tag: *pt, fallback: j + 1, index: item.index}
+		switch ref.source {
+		case annotation:
+			c.AnnotationFastAccess[ref.value] = append(c.AnnotationFastAccess[ref.value], fallback)
+		case label:
+			c.LabelsFastAccess[ref.value] = append(c.LabelsFastAccess[ref.value], fallback)
+		}
+	}
+}
+
+// loadCache returns cache of root type stored in caches. If it does not exist, new cache is built with codecs
+// registered in the Encoder or Decoder owning caches and stored in caches.
+func loadCache[C any](caches *sync.Map, root reflect.Type, codecs map[reflect.Type]C) (*typeCache, error) {
+	if c, ok := caches.Load(root); ok {
+		return c.(*typeCache), nil
+	}
+	c, err := newTypeCache(root, func(t reflect.Type) bool {
+		_, ok := codecs[t]
+		return ok
 	})
-	if err == nil {
-		c.CachedType = root
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := caches.LoadOrStore(root, c)
+	return actual.(*typeCache), nil
+}
+
+// withKeyFunc returns copy of cache with annotation and label keys of all fields transformed by fn.
+func (c *typeCache) withKeyFunc(fn func(src source, key string) string) *typeCache {
+	n := &typeCache{
+		CachedType:           c.CachedType,
+		AnnotationFastAccess: map[string][]fieldInfo{},
+		LabelsFastAccess:     map[string][]fieldInfo{},
+	}
+	for _, info := range c.Fields {
+		n.register(fieldInfo{path: info.path, tag: *info.tag.withKeyFunc(fn)})
+	}
+	return n
+}
+
+// validateKeys checks if all annotation and label keys (including aliases) referenced by cached type
+// are valid Kubernetes keys.
+func (c *typeCache) validateKeys() error {
+	var fieldErrors field.ErrorList
+	fieldErrors = append(fieldErrors, validateKeySet(c.AnnotationFastAccess, annotation)...)
+	fieldErrors = append(fieldErrors, validateKeySet(c.LabelsFastAccess, label)...)
+	presence := map[string][]fieldInfo{}
+	for _, info := range c.LabelPresenceFastAccess {
+		presence[info.tag.value] = append(presence[info.tag.value], info)
+	}
+	fieldErrors = append(fieldErrors, validateKeySet(presence, labelPresence)...)
+	if len(fieldErrors) > 0 {
+		return &fieldError{message: fieldErrors.ToAggregate().Error(), fieldErrors: fieldErrors}
+	}
+	return nil
+}
+
+// validateKeySet validates keys the same way as Kubernetes API server does, which lowercases only annotation keys.
+func validateKeySet(keys map[string][]fieldInfo, src source) field.ErrorList {
+	var fieldErrors field.ErrorList
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		name := k
+		if src == annotation {
+			name = strings.ToLower(k)
+		}
+		for _, msg := range validation.IsQualifiedName(name) {
+			fieldErrors = append(fieldErrors, field.Invalid(field.NewPath("metadata").Child(src.String()), k, msg))
+		}
 	}
-	return c, err
+	return fieldErrors
 }
@@ -0,0 +1,291 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type cacheFirst struct {
+	A int    `k8s:"annotation:a"`
+	B string `k8s:"label:b"`
+}
+
+type cacheSecond struct {
+	A string `k8s:"annotation:a"`
+	C bool   `k8s:"labelpresence:c"`
+}
+
+var _ = Describe("Cache of multiple types", func() {
+	It("should keep caches of alternating types", func() {
+		dec := NewDecoder()
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "1"}, Labels: map[string]string{"b": "x", "c": ""}}
+		for i := 0; i < 3; i++ {
+			first, second := cacheFirst{}, cacheSecond{}
+			Expect(dec.Decode(m, &first)).To(Succeed())
+			Expect(dec.Decode(m, &second)).To(Succeed())
+			Expect(first).To(Equal(cacheFirst{A: 1, B: "x"}))
+			Expect(second).To(Equal(cacheSecond{A: "1", C: true}))
+		}
+		c1, err := loadCache(&dec.cache, reflect.TypeOf(&cacheFirst{}), dec.codecs)
+		Expect(err).ToNot(HaveOccurred())
+		c2, err := loadCache(&dec.cache, reflect.TypeOf(&cacheFirst{}), dec.codecs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1).To(BeIdenticalTo(c2))
+	})
+	It("should decode and encode multiple types concurrently", func() {
+		dec, enc := NewDecoder(), NewEncoder()
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "1"}, Labels: map[string]string{"b": "x", "c": ""}}
+		var wg sync.WaitGroup
+		errs := make(chan error, 100)
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				v := cacheFirst{}
+				if err := dec.Decode(m, &v); err != nil || v != (cacheFirst{A: 1, B: "x"}) {
+					errs <- fmt.Errorf("unexpected result %+v: %v", v, err)
+				}
+				errs <- enc.Encode(&v, &metav1.ObjectMeta{})
+			}()
+			go func() {
+				defer wg.Done()
+				v := cacheSecond{}
+				if err := dec.Decode(m, &v); err != nil || v != (cacheSecond{A: "1", C: true}) {
+					errs <- fmt.Errorf("unexpected result %+v: %v", v, err)
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+})
+
+func BenchmarkDecodeAlternatingTypes(b *testing.B) {
+	dec := NewDecoder()
+	m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "1"}, Labels: map[string]string{"b": "x", "c": ""}}
+	first, second := cacheFirst{}, cacheSecond{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := dec.Decode(m, &first); err != nil {
+			b.Fatal(err)
+		}
+		if err := dec.Decode(m, &second); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var _ = Describe("Building cache for unexported fields", func() {
+	type Inner struct {
+		A string `k8s:"annotation:a"`
+	}
+	type inner struct {
+		B string `k8s:"annotation:b"`
+	}
+
+	It("should return descriptive error for tagged unexported field", func() {
+		s := struct {
+			value int `k8s:"annotation:value"`
+		}{}
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"value": "1"}}, &s)
+		Expect(err).To(MatchError(ContainSubstring("field 'value' of type 'struct { value int")))
+		Expect(err).To(MatchError(ContainSubstring("unexported fields cannot be tagged")))
+		Expect(Marshal(&s, &metav1.ObjectMeta{})).To(MatchError(ContainSubstring("unexported fields cannot be tagged")))
+	})
+	It("should return error for unexported struct containing tagged fields", func() {
+		s := struct {
+			Name  string `k8s:"name"`
+			inner Inner
+		}{}
+		err := Unmarshal(&metav1.ObjectMeta{}, &s)
+		Expect(err).To(MatchError(ContainSubstring("field 'inner' of type")))
+		Expect(err).To(MatchError(ContainSubstring("unexported fields cannot contain tagged fields")))
+	})
+	It("should accept embedded unexported struct and untagged unexported fields", func() {
+		type S struct {
+			Name string `k8s:"name"`
+			inner
+			note string
+		}
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Name: "n", Annotations: map[string]string{"b": "x"}}, &s)).To(Succeed())
+		Expect(s.B).To(Equal("x"))
+	})
+})
+
+type flatStruct struct {
+	Replicas int     `k8s:"annotation:replicas"`
+	Ratio    float64 `k8s:"annotation:ratio"`
+	Owner    string  `k8s:"annotation:owner"`
+	Enabled  bool    `k8s:"label:enabled"`
+	Tier     string  `k8s:"label:tier"`
+}
+
+// inlineStruct has the same fields as flatStruct, but they are decoded by general path.
+type inlineStruct struct {
+	Inner struct {
+		Replicas int     `k8s:"annotation:replicas"`
+		Ratio    float64 `k8s:"annotation:ratio"`
+		Owner    string  `k8s:"annotation:owner"`
+	} `k8s:"inline"`
+	Enabled bool   `k8s:"label:enabled"`
+	Tier    string `k8s:"label:tier"`
+}
+
+var flatMeta = &metav1.ObjectMeta{
+	Annotations: map[string]string{"replicas": "3", "ratio": "0.5", "owner": "alice", "unrelated": "x"},
+	Labels:      map[string]string{"enabled": "true", "tier": "gold"},
+}
+
+func BenchmarkDecodeFlat(b *testing.B) {
+	dec := NewDecoder()
+	s := flatStruct{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := dec.Decode(flatMeta, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var _ = Describe("Decoding flat types", func() {
+	It("should detect flat types", func() {
+		c, err := newTypeCache(reflect.TypeOf(&flatStruct{}), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Flat).To(BeTrue())
+		c, err = newTypeCache(reflect.TypeOf(&struct {
+			V *int `k8s:"annotation:v"`
+		}{}), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Flat).To(BeFalse())
+		c, err = newTypeCache(reflect.TypeOf(&struct {
+			V string `k8s:"annotation:v,aliases:w"`
+		}{}), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Flat).To(BeFalse())
+	})
+	It("should produce the same result as general path", func() {
+		fast, general := flatStruct{Tier: "keep"}, flatStruct{Tier: "keep"}
+		m := &metav1.ObjectMeta{Annotations: flatMeta.Annotations, Labels: map[string]string{"enabled": "true"}}
+		Expect(Unmarshal(m, &fast)).To(Succeed())
+		// tracing disables fast path
+		Expect(Unmarshal(m, &general, Trace(&[]TraceEvent{}))).To(Succeed())
+		Expect(fast).To(Equal(general))
+		Expect(fast).To(Equal(flatStruct{Replicas: 3, Ratio: 0.5, Owner: "alice", Enabled: true, Tier: "keep"}))
+	})
+	It("should return the same errors as general path", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"replicas": "x", "ratio": "y"}}
+		fastErr := Unmarshal(m, &flatStruct{}, AccumulateFieldErrors())
+		generalErr := Unmarshal(m, &flatStruct{}, AccumulateFieldErrors(), Trace(&[]TraceEvent{}))
+		Expect(GetErrorList(fastErr)).To(ConsistOf(GetErrorList(generalErr)))
+		Expect(GetErrorList(fastErr)).To(HaveLen(2))
+	})
+})
+
+func BenchmarkDecodeInline(b *testing.B) {
+	dec := NewDecoder()
+	s := inlineStruct{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := dec.Decode(flatMeta, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var _ = Describe("Building cache for sibling fields", func() {
+	type Deep struct {
+		A string `k8s:"annotation:deep-a"`
+		B string `k8s:"annotation:deep-b"`
+		C string `k8s:"annotation:deep-c"`
+		D string `k8s:"annotation:deep-d"`
+		E string `k8s:"annotation:deep-e"`
+	}
+	type Middle struct {
+		A    string `k8s:"annotation:middle-a"`
+		Deep Deep   `k8s:"inline"`
+		B    string `k8s:"annotation:middle-b"`
+		C    string `k8s:"annotation:middle-c"`
+	}
+	type S struct {
+		First  Middle `k8s:"inline,prefix:first-"`
+		Second Middle `k8s:"inline,prefix:second-"`
+	}
+
+	It("should store independent paths for sibling fields", func() {
+		c, err := newTypeCache(reflect.TypeOf(&S{}), nil)
+		Expect(err).NotTo(HaveOccurred())
+		paths := map[string][]int{}
+		for _, info := range c.Fields {
+			if !info.tag.inline {
+				paths[info.tag.value] = info.path
+			}
+		}
+		Expect(paths).To(HaveLen(16))
+		Expect(paths["first-deep-a"]).To(Equal([]int{0, 1, 0}))
+		Expect(paths["first-deep-e"]).To(Equal([]int{0, 1, 4}))
+		Expect(paths["second-middle-c"]).To(Equal([]int{1, 3}))
+		Expect(paths["second-deep-d"]).To(Equal([]int{1, 1, 3}))
+	})
+	It("should decode every sibling into its own field", func() {
+		annotations := map[string]string{}
+		for _, p := range []string{"first-", "second-"} {
+			for _, k := range []string{"middle-a", "middle-b", "middle-c", "deep-a", "deep-b", "deep-c", "deep-d", "deep-e"} {
+				annotations[p+k] = p + k
+			}
+		}
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: annotations}, &s)).To(Succeed())
+		Expect(s.First.Deep).To(Equal(Deep{A: "first-deep-a", B: "first-deep-b", C: "first-deep-c", D: "first-deep-d", E: "first-deep-e"}))
+		Expect(s.Second.C).To(Equal("second-middle-c"))
+		Expect(s.Second.Deep.E).To(Equal("second-deep-e"))
+	})
+})
+
+var _ = Describe("Building cache for types embedding k8s metadata", func() {
+	type S struct {
+		metav1.TypeMeta
+		metav1.ObjectMeta
+		Mode string `k8s:"annotation:mode"`
+	}
+
+	It("should not walk fields of embedded ObjectMeta and TypeMeta", func() {
+		c, err := newTypeCache(reflect.TypeOf(&S{}), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Fields).To(HaveLen(1))
+		Expect(c.Fields[0].path).To(Equal([]int{2}))
+		Expect(c.Flat).To(BeTrue())
+	})
+	It("should decode tagged siblings of embedded ObjectMeta", func() {
+		s := S{}
+		s.Name = "kept"
+		Expect(Unmarshal(&metav1.ObjectMeta{Name: "obj", Annotations: map[string]string{"mode": "on"}}, &s)).To(Succeed())
+		Expect(s.Mode).To(Equal("on"))
+		Expect(s.ObjectMeta).To(Equal(metav1.ObjectMeta{Name: "kept"}))
+	})
+})
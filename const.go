@@ -17,25 +17,61 @@ limitations under the License.
 package metaser
 
 const (
-	k8sKey            = "k8s"
-	nameKey           = "name"
-	namespaceKey      = "namespace"
-	dataKey           = "data"
-	annotationKey     = "annotation"
-	labelKey          = "label"
-	inKey             = "in"
-	outKey            = "out"
-	inoutKey          = "inout"
-	encodingKey       = "enc"
-	jsonKey           = "json"
-	customKey         = "custom"
-	inlineKey         = "inline"
-	itemSeparator     = ","
-	keyValueSeparator = ":"
-	omitEmptyKey      = "omitempty"
-	immutableKey      = "immutable"
-	aliasesKey        = "aliases"
-	setOnceKey        = "setonce"
+	k8sKey               = "k8s"
+	nameKey              = "name"
+	namespaceKey         = "namespace"
+	dataKey              = "data"
+	annotationKey        = "annotation"
+	labelsKey            = "labels"
+	annotationsKey       = "annotations"
+	labelKey             = "label"
+	ownerKey             = "owner"
+	creationTimestampKey = "creationtimestamp"
+	deletionTimestampKey = "deletiontimestamp"
+	finalizersKey        = "finalizers"
+	generationKey        = "generation"
+	labelPresenceKey     = "labelpresence"
+	absentKey            = "absent"
+	inKey                = "in"
+	outKey               = "out"
+	inoutKey             = "inout"
+	encodingKey          = "enc"
+	jsonKey              = "json"
+	indentKey            = "indent"
+	jsonIndent           = "  "
+	binaryKey            = "binary"
+	hexKey               = "hex"
+	baseKey              = "base"
+	ttlKey               = "ttl"
+	humanIntKey          = "humanint"
+	stringerKey          = "stringer"
+	labelSetKey          = "labelset"
+	orderedMapKey        = "orderedmap"
+	boolKey              = "bool"
+	boolTokenSeparator   = "/"
+	customKey            = "custom"
+	inlineKey            = "inline"
+	prefixKey            = "prefix"
+	itemSeparator        = ","
+	sourceSeparator      = "|"
+	keyValueSeparator    = ":"
+	omitEmptyKey         = "omitempty"
+	immutableKey         = "immutable"
+	aliasesKey           = "aliases"
+	aliasFirstKey        = "aliasfirst"
+	setOnceKey           = "setonce"
+	percentIntKey        = "percentint"
+	suffixKey            = "suffix"
+	percentSuffix        = "%"
+	jsonSentinel         = "@json:"
+	providerKey          = "src"
+	providerSeparator    = "="
+	maxBytesKey          = "maxbytes"
+	dns1123LabelKey      = "dns1123label"
+	dns1123SubdomainKey  = "dns1123subdomain"
+	foldKey              = "fold"
+	templateKey          = "template"
+	defaultKey           = "default"
 )
 
 type source int
@@ -49,6 +85,15 @@ const (
 	namespace
 	annotation
 	label
+	owner
+	labelPresence
+	provided
+	creationTimestamp
+	allLabels
+	allAnnotations
+	deletionTimestamp
+	finalizers
+	generation
 )
 
 const (
@@ -60,6 +105,17 @@ const (
 const (
 	jsonEnc encoder = iota + 1
 	custom
+	binaryEnc
+	ttlEnc
+	percentEnc
+	percentSuffixEnc
+	baseEnc
+	hexEnc
+	humanIntEnc
+	boolEnc
+	stringerEnc
+	labelSetEnc
+	orderedMapEnc
 )
 
 func (s source) String() string {
@@ -72,6 +128,64 @@ func (s source) String() string {
 		return annotationKey
 	case label:
 		return labelKey
+	case owner:
+		return ownerKey
+	case labelPresence:
+		return labelPresenceKey
+	case provided:
+		return providerKey
+	case creationTimestamp:
+		return creationTimestampKey
+	case allLabels:
+		return labelsKey
+	case allAnnotations:
+		return annotationsKey
+	case deletionTimestamp:
+		return deletionTimestampKey
+	case finalizers:
+		return finalizersKey
+	case generation:
+		return generationKey
 	}
 	return "undefined source"
 }
+
+func (d dir) String() string {
+	switch d {
+	case in:
+		return inKey
+	case out:
+		return outKey
+	}
+	return inoutKey
+}
+
+func (e encoder) String() string {
+	switch e {
+	case jsonEnc:
+		return jsonKey
+	case custom:
+		return customKey
+	case binaryEnc:
+		return binaryKey
+	case ttlEnc:
+		return ttlKey
+	case percentEnc, percentSuffixEnc:
+		return percentIntKey
+	case baseEnc:
+		return baseKey
+	case hexEnc:
+		return hexKey
+	case humanIntEnc:
+		return humanIntKey
+	case boolEnc:
+		return boolKey
+	case stringerEnc:
+		return stringerKey
+	case labelSetEnc:
+		return labelSetKey
+	case orderedMapEnc:
+		return orderedMapKey
+	}
+	return "default"
+}
@@ -17,9 +17,16 @@ limitations under the License.
 package metaser
 
 import (
+	"strconv"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// customItemPrefix is a prefix of annotation and label keys belonging to elements of custom-encoded slices.
+// Keys of element at index i of field with path <field> are stored in metadata as "<field>.item-<i>-<key>".
+const customItemPrefix = "item-"
+
 type MetadataUnmarshaler interface {
 	UnmarshalFromMetadata(meta metav1.Object) error
 }
@@ -27,3 +34,66 @@ type MetadataUnmarshaler interface {
 type MetadataMarshaler interface {
 	MarshalToMetadata(meta metav1.Object) error
 }
+
+func customItemKey(field string, index int, key string) string {
+	return field + "." + customItemPrefix + strconv.Itoa(index) + "-" + key
+}
+
+// parseCustomItemKey splits "<field>.item-<i>-<key>" into index and key. Keys of other fields are not matched.
+func parseCustomItemKey(field, key string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(key, field+"."+customItemPrefix)
+	if !ok {
+		return 0, "", false
+	}
+	idx, subkey, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, "", false
+	}
+	i, err := strconv.Atoi(idx)
+	if err != nil || i < 0 {
+		return 0, "", false
+	}
+	return i, subkey, true
+}
+
+// customItemsCount returns number of elements of custom-encoded slice field stored in metadata.
+func customItemsCount(meta metav1.Object, field string) int {
+	n := 0
+	for _, values := range []map[string]string{meta.GetAnnotations(), meta.GetLabels()} {
+		for k := range values {
+			if i, _, ok := parseCustomItemKey(field, k); ok && i >= n {
+				n = i + 1
+			}
+		}
+	}
+	return n
+}
+
+// customItemMeta returns view of metadata containing only annotations and labels of element of field at index.
+func customItemMeta(meta metav1.Object, field string, index int) *metav1.ObjectMeta {
+	view := &metav1.ObjectMeta{
+		Name:        meta.GetName(),
+		Namespace:   meta.GetNamespace(),
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	scope := func(in, out map[string]string) {
+		for k, v := range in {
+			if i, subkey, ok := parseCustomItemKey(field, k); ok && i == index {
+				out[subkey] = v
+			}
+		}
+	}
+	scope(meta.GetAnnotations(), view.Annotations)
+	scope(meta.GetLabels(), view.Labels)
+	return view
+}
+
+// clearCustomItems removes elements of custom-encoded slice field from values.
+func clearCustomItems(values map[string]string, field string) {
+	for k := range values {
+		if _, _, ok := parseCustomItemKey(field, k); ok {
+			delete(values, k)
+		}
+	}
+}
@@ -17,15 +17,21 @@ limitations under the License.
 package metaser
 
 import (
+	"context"
 	"encoding"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"math/bits"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
-	"sync/atomic"
+	"sync"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
@@ -33,19 +39,42 @@ import (
 
 // Decoder reads and decodes data from Kubernets Resource metatdata
 type Decoder struct {
-	cache atomic.Pointer[cache]
+	// cache maps reflect.Type of every processed type to its *typeCache.
+	cache     sync.Map
+	now       func() time.Time
+	codecs    map[reflect.Type]func(out reflect.Value, in string) error
+	stringers map[reflect.Type]map[string]reflect.Value
+	defaults  defaults
+	strict    bool
 }
 
 // internal struct represents context of decoding operation.
 type decodeContext struct {
 	root                  reflect.Value
-	cache                 *cache
+	cache                 *typeCache
 	meta                  metav1.Object
 	fieldErrors           field.ErrorList
+	errorFields           map[string]*field.Error
 	performValidation     bool
 	accumulateFieldErrors bool
 	skipDefaultWorkload   bool
+	validateKeys          bool
+	mergeCollections      bool
+	autoJSON              bool
+	trace                 *[]TraceEvent
+	touched               *[]string
+	ctx                   context.Context
+	normalizeKey          func(string) string
+	keyFunc               func(src source, key string) string
+	disallowUnknownKeys   bool
+	caseInsensitive       bool
+	codecs                map[reflect.Type]func(out reflect.Value, in string) error
+	stringers             map[reflect.Type]map[string]reflect.Value
+	defaults              defaults
+	unknownKeysPrefix     string
+	providers             map[string]SourceProvider
 	filter                fieldFilter
+	now                   func() time.Time
 }
 
 // DecodeOption to be passed to Decode()
@@ -85,6 +114,89 @@ func DecodeImmutablesOnly() DecodeOption {
 	}
 }
 
+// ValidateKeys enforces decoder to check if annotation and label keys (including aliases) used in struct tags
+// are valid Kubernetes keys before decoding.
+func ValidateKeys() DecodeOption {
+	return func(dec *decodeContext) {
+		dec.validateKeys = true
+	}
+}
+
+// MergeCollections enforces decoder to append decoded elements to existing slices and
+// merge decoded items into existing maps instead of replacing them.
+func MergeCollections() DecodeOption {
+	return func(dec *decodeContext) {
+		dec.mergeCollections = true
+	}
+}
+
+// WithKeyNormalizer enforces decoder to transform annotation and label keys of metadata and keys used in struct tags
+// (including aliases and alternative sources) with normalize before matching them. It allows to decode metadata
+// with mixed key conventions (e.g. camelCase, kebab-case or snake_case). Note that normalization may map different
+// keys to the same one. Decode returns an error when it happens for keys present in metadata, while ambiguous
+// keys in struct tags are decoded in unspecified order.
+func WithKeyNormalizer(normalize func(string) string) DecodeOption {
+	return func(dec *decodeContext) {
+		dec.normalizeKey = normalize
+	}
+}
+
+// DecodeKeyFunc enforces decoder to transform annotation and label keys used in struct tags (including aliases
+// and alternative sources) with fn before reading them from metadata. fn is called with "annotation" or "label"
+// as source and the key. Keys of metadata are not transformed. It allows to e.g. add organization-specific prefix
+// to keys for single Decode call.
+func DecodeKeyFunc(fn func(source, key string) string) DecodeOption {
+	return func(dec *decodeContext) {
+		dec.keyFunc = func(src source, key string) string { return fn(src.String(), key) }
+	}
+}
+
+// DisallowUnknownKeys enforces decoder to return an error when metadata contains annotation starting with prefix
+// which does not correspond to any field (including aliases and alternative sources). Keys of custom-encoded fields
+// are not known upfront, so they are reported as unknown too. The check is skipped when the type contains field
+// tagged with 'annotations'. With AccumulateFieldErrors all unknown keys are reported.
+func DisallowUnknownKeys(prefix string) DecodeOption {
+	return func(dec *decodeContext) {
+		dec.disallowUnknownKeys = true
+		dec.unknownKeysPrefix = prefix
+	}
+}
+
+// CaseInsensitive enforces decoder to lowercase annotation and label values before assigning them to fields tagged
+// with 'fold'. It allows to accept values like "Enabled" or "ENABLED" for string-based enums. Fields without 'fold'
+// are not affected.
+func CaseInsensitive() DecodeOption {
+	return func(dec *decodeContext) {
+		dec.caseInsensitive = true
+	}
+}
+
+// RecordTouched enforces decoder to append into 'into' dot separated paths of struct fields which keys were present
+// in metadata and were decoded successfully. Paths are sorted and every field is recorded once, even if several of its
+// keys (aliases or keys of range) are present. Fields decoded from sources without keys (custom, 'labels',
+// 'annotations', creation timestamp and providers) are not recorded.
+func RecordTouched(into *[]string) DecodeOption {
+	return func(dec *decodeContext) {
+		dec.touched = into
+	}
+}
+
+// DecodeAutoJSONCollections enforces decoder to decode slices, arrays and maps using default encoding scheme as JSON
+// when their value is prefixed with "@json:", which is written by AutoJSONCollections encoder option. Without this
+// option the prefix has no special meaning, so e.g. "@json:x" is decoded as single string element.
+func DecodeAutoJSONCollections() DecodeOption {
+	return func(dec *decodeContext) {
+		dec.autoJSON = true
+	}
+}
+
+// DecodeClock sets source of current time used by time-dependent decoders (e.g. 'ttl').
+func DecodeClock(now func() time.Time) DecodeOption {
+	return func(dec *decodeContext) {
+		dec.now = now
+	}
+}
+
 func assignToBool(out reflect.Value, in string) error {
 	v, err := strconv.ParseBool(in)
 	if err == nil {
@@ -117,32 +229,76 @@ func assignToFloat(out reflect.Value, in string, bits int) error {
 	return err
 }
 
-func assignToArray(out reflect.Value, in string) error {
+func assignToArray(dc *decodeContext, out reflect.Value, in string) error {
+	if err := checkStructElem(out.Type(), dc.codecs); err != nil {
+		return err
+	}
+	if in == "" {
+		if out.Len() != 0 {
+			return errors.New("array elements number do not match")
+		}
+		return nil
+	}
 	values := strings.Split(in, itemSeparator)
 	if out.Len() != len(values) {
 		return errors.New("array elements number do not match")
 	}
 	for i, value := range values {
-		if err := decodeUndefined(out.Index(i), value); err != nil {
+		if err := decodeUndefined(dc, out.Index(i), value); err != nil {
 			return fmt.Errorf("unable to decode array index %d, value: '%s': [%w]", i, value, err)
 		}
 	}
 	return nil
 }
 
-func assignToSlice(out reflect.Value, in string) error {
+func assignToSlice(dc *decodeContext, out reflect.Value, in string) error {
+	if err := checkStructElem(out.Type(), dc.codecs); err != nil {
+		return err
+	}
+	merge := dc.mergeCollections && !out.IsNil()
+	if in == "" {
+		if !merge {
+			out.Set(reflect.MakeSlice(out.Type(), 0, 0))
+		}
+		return nil
+	}
 	values := strings.Split(in, itemSeparator)
 	slice := reflect.MakeSlice(out.Type(), len(values), len(values))
 	for i, value := range values {
-		if err := decodeUndefined(slice.Index(i), value); err != nil {
+		if err := decodeUndefined(dc, slice.Index(i), value); err != nil {
 			return fmt.Errorf("unable to decode slice index %d, value: '%s': [%w]", i, value, err)
 		}
 	}
+	if merge {
+		slice = reflect.AppendSlice(out, slice)
+	}
 	out.Set(slice)
 	return nil
 }
 
-func assignToMap(out reflect.Value, in string) error {
+// assignToBytes stores raw bytes of in into byte slice or array. Length of array must match length of in.
+func assignToBytes(out reflect.Value, in string) error {
+	if out.Kind() == reflect.Slice {
+		out.SetBytes([]byte(in))
+		return nil
+	}
+	if out.Len() != len(in) {
+		return fmt.Errorf("byte array of length %d cannot hold %d bytes", out.Len(), len(in))
+	}
+	for i := 0; i < len(in); i++ {
+		out.Index(i).SetUint(uint64(in[i]))
+	}
+	return nil
+}
+
+func assignToMap(dc *decodeContext, out reflect.Value, in string) error {
+	if in == "" {
+		// empty map is encoded as empty string
+		if !dc.mergeCollections || out.IsNil() {
+			out.Set(reflect.MakeMap(out.Type()))
+		}
+		return nil
+	}
 	values := strings.Split(in, itemSeparator)
 	mp := reflect.MakeMapWithSize(out.Type(), len(values))
 	for _, value := range values {
@@ -150,31 +306,58 @@ func assignToMap(out reflect.Value, in string) error {
 		if len(elem) != 2 {
 			return fmt.Errorf("invalid map item syntax, expected <key>:<value>, got: %s", value)
 		}
+		key := reflect.New(mp.Type().Key()).Elem()
+		if err := decodeUndefined(dc, key, elem[0]); err != nil {
+			return fmt.Errorf("unable to decode map key '%s': [%w]", elem[0], err)
+		}
 		value := reflect.New(mp.Type().Elem()).Elem()
-		if err := decodeUndefined(value, elem[1]); err != nil {
+		if err := decodeUndefined(dc, value, elem[1]); err != nil {
 			return fmt.Errorf("unable to decode map item (key '%s', value: '%s'): [%w]", elem[0], elem[1], err)
 		}
-		mp.SetMapIndex(reflect.ValueOf(elem[0]), value)
+		mp.SetMapIndex(key, value)
+	}
+	if dc.mergeCollections && !out.IsNil() {
+		iter := mp.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return nil
 	}
 	out.Set(mp)
 	return nil
 }
 
-func assignToPointer(out reflect.Value, in string) error {
+func assignToPointer(dc *decodeContext, out reflect.Value, in string) error {
 	var realValue reflect.Value
 	if out.IsZero() {
 		realValue = reflect.New(out.Type().Elem())
 	} else {
 		realValue = out
 	}
-	if err := decodePrimitive(realValue.Elem(), in); err != nil {
+	if err := decodeUndefined(dc, realValue.Elem(), in); err != nil {
 		return fmt.Errorf("cannot assign value to pointer: [%w]", err)
 	}
 	out.Set(realValue)
 	return nil
 }
 
-func decodePrimitive(out reflect.Value, in string) error {
+func decodePrimitive(dc *decodeContext, out reflect.Value, in string) error {
+	if isBytes(out.Type()) {
+		return assignToBytes(out, in)
+	}
+	// collection serialized as JSON by AutoJSONCollections encoder option
+	if k := out.Kind(); dc.autoJSON && (k == reflect.Array || k == reflect.Slice || k == reflect.Map) {
+		if data, ok := strings.CutPrefix(in, jsonSentinel); ok {
+			return decodeJson(out, data)
+		}
+	}
+	if out.Type() == metaDurationType {
+		d, err := time.ParseDuration(in)
+		if err == nil {
+			out.Field(0).SetInt(int64(d))
+		}
+		return err
+	}
 	switch out.Kind() {
 	case reflect.Bool:
 		return assignToBool(out, in)
@@ -203,17 +386,22 @@ func decodePrimitive(out reflect.Value, in string) error {
 	case reflect.Float64:
 		return assignToFloat(out, in, 64)
 	case reflect.Array:
-		return assignToArray(out, in)
+		return assignToArray(dc, out, in)
 	case reflect.Map:
-		return assignToMap(out, in)
+		return assignToMap(dc, out, in)
 	case reflect.Pointer:
-		return assignToPointer(out, in)
+		return assignToPointer(dc, out, in)
 	case reflect.Slice:
-		return assignToSlice(out, in)
+		return assignToSlice(dc, out, in)
 	case reflect.String:
 		out.SetString(in)
+	case reflect.Interface:
+		if out.IsNil() {
+			return fmt.Errorf("cannot decode into interface type '%s': concrete type is unknown", out.Type())
+		}
+		return decodeCopy(dc, out, out.Elem(), in)
 	default:
-		return errors.New("unsupported type")
+		return &UnsupportedTypeError{Type: out.Type()}
 	}
 	return nil
 }
@@ -239,40 +427,146 @@ func decodeUsingTextUnmarshaler(out reflect.Value, in string) error {
 	return nil
 }
 
-func decodeUndefined(out reflect.Value, in string) error {
+func decodeUndefined(dc *decodeContext, out reflect.Value, in string) error {
 	if !out.IsValid() {
 		return errors.New("unable to decode to invalid value")
 	}
-	// first try to check if TextUnmarshaler is defined for type
+	if codec, ok := dc.codecs[out.Type()]; ok {
+		return codec(out, in)
+	}
+	if out.Kind() == reflect.Pointer && dc.codecs[out.Type().Elem()] != nil {
+		// pointer is allocated and dereferenced, so codec is not bypassed by methods of pointer type
+		return decodePrimitive(dc, out, in)
+	}
+	// then try to check if TextUnmarshaler is defined for type
 	if implements[encoding.TextUnmarshaler](out) {
 		return decodeUsingTextUnmarshaler(out, in)
 	}
 	if isOption(out) {
-		return decodeOption(out, in)
+		return decodeOption(dc, out, in)
 	}
-	return decodePrimitive(out, in)
+	return decodePrimitive(dc, out, in)
 }
 
-func decodeOption(out reflect.Value, in string) error {
-	err := decodeUndefined(asWritableValue(out.FieldByName("value")), in)
+// decodeCopy decodes in into addressable copy of value and sets the copy back to parent, which holds value.
+func decodeCopy(dc *decodeContext, parent, value reflect.Value, in string) error {
+	cp := reflect.New(value.Type()).Elem()
+	cp.Set(value)
+	if err := decodeUndefined(dc, cp, in); err != nil {
+		return err
+	}
+	parent.Set(cp)
+	return nil
+}
+
+// decodeOption decodes value of Option and marks it as set. It is called only for keys present in metadata,
+// so present key with empty value is decoded as Some("") for string options, while absent key leaves Option unset.
+// Option held by interface is not addressable, so it is decoded into a copy set back to the interface (see decodeCopy).
+// Other non-addressable Option has no parent to be set back to, so an error is returned instead of panicking.
+func decodeOption(dc *decodeContext, out reflect.Value, in string) error {
+	if !out.CanAddr() {
+		return fmt.Errorf("cannot decode into non-addressable '%s'", out.Type())
+	}
+	err := decodeUndefined(dc, asWritableValue(out.Field(valueFieldIndex)), in)
 	if err == nil {
-		asWritableValue(out.FieldByName("isSet")).SetBool(true)
+		asWritableValue(out.Field(isSetFieldIndex)).SetBool(true)
 	}
 	return err
 }
 
 func decodeJson(out reflect.Value, in string) error {
-	if out.Kind() == reflect.Pointer && out.IsNil() {
-		out.Set(reflect.New(out.Type().Elem()))
+	if t := out.Type(); t == rawJsonType || t == reflect.PointerTo(rawJsonType) {
+		// json.RawMessage is read verbatim
+		if out.Kind() == reflect.Pointer {
+			if out.IsNil() {
+				out.Set(reflect.New(rawJsonType))
+			}
+			out = out.Elem()
+		}
+		out.SetBytes([]byte(in))
+		return nil
+	}
+	// existing pointee is unmarshaled in place, so its fields absent in json are preserved
+	if out.Kind() == reflect.Pointer {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
 	} else {
 		out = out.Addr()
 	}
 	return json.Unmarshal([]byte(in), out.Interface())
 }
 
-func decodeCustom(out reflect.Value, meta metav1.Object) error {
+// decodeHex decodes hex string into byte slice or array.
+func decodeHex(out reflect.Value, in string) error {
+	if out.Kind() == reflect.Pointer {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	if !isBytes(out.Type()) {
+		return fmt.Errorf("hex encoding requires byte slice or array, got '%s'", out.Type())
+	}
+	data, err := hex.DecodeString(in)
+	if err != nil {
+		return fmt.Errorf("invalid hex value: [%w]", err)
+	}
+	if out.Kind() == reflect.Array {
+		if out.Len() != len(data) {
+			return fmt.Errorf("invalid hex value length. Expected %d bytes, got %d", out.Len(), len(data))
+		}
+		reflect.Copy(out, reflect.ValueOf(data))
+		return nil
+	}
+	out.SetBytes(data)
+	return nil
+}
+
+func decodeBinary(out reflect.Value, in string) error {
 	var fun reflect.Value
 
+	data, err := base64.StdEncoding.DecodeString(in)
+	if err != nil {
+		return fmt.Errorf("invalid base64 value: [%w]", err)
+	}
+
+	if out.Kind() == reflect.Pointer && out.IsNil() {
+		out.Set(reflect.New(out.Type().Elem()))
+	}
+
+	fun = method(out, "UnmarshalBinary")
+	if !fun.IsValid() || fun.IsZero() {
+		return fmt.Errorf("type '%s' nor '*%s' doesn't implement encoding.BinaryUnmarshaler", out.Type().Name(), out.Type().Name())
+	}
+	ret := fun.Call([]reflect.Value{reflect.ValueOf(data)})
+	if len(ret) != 1 {
+		return fmt.Errorf("expected single return value, got %d", len(ret))
+	}
+	if err, ok := ret[0].Interface().(error); ok {
+		return fmt.Errorf("failed to deserialize with encoding.BinaryUnmarshaler interface: [%w]", err)
+	}
+	return nil
+}
+
+// decodeCustom deserializes out with metaser.MetadataUnmarshaler interface. Elements of slices are deserialized from
+// keys scoped by field path.
+func decodeCustom(out reflect.Value, meta metav1.Object, field string) error {
+	var fun reflect.Value
+
+	if out.Kind() == reflect.Slice && !method(out, "UnmarshalFromMetadata").IsValid() {
+		return decodeCustomSlice(out, meta, field)
+	}
+
+	// Option is set only after its value was successfully deserialized
+	if isOption(out) {
+		if err := decodeCustom(asWritableValue(out.Field(valueFieldIndex)), meta, field); err != nil {
+			return err
+		}
+		asWritableValue(out.Field(isSetFieldIndex)).SetBool(true)
+		return nil
+	}
+
 	if out.Kind() == reflect.Pointer && out.IsNil() {
 		out.Set(reflect.New(out.Type().Elem()))
 	}
@@ -291,26 +585,199 @@ func decodeCustom(out reflect.Value, meta metav1.Object) error {
 	return nil
 }
 
-func decodeWithEncoder(out reflect.Value, in string, enc encoder) error {
+func decodeCustomSlice(out reflect.Value, meta metav1.Object, field string) error {
+	n := customItemsCount(meta, field)
+	if n == 0 {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+	slice := reflect.MakeSlice(out.Type(), n, n)
+	for i := 0; i < n; i++ {
+		if err := decodeCustom(slice.Index(i), customItemMeta(meta, field, i), field); err != nil {
+			return fmt.Errorf("unable to decode slice index %d: [%w]", i, err)
+		}
+	}
+	out.Set(slice)
+	return nil
+}
+
+// decodeTTL decodes expiry timestamp into time.Time or into time.Duration remaining until expiry.
+func decodeTTL(out reflect.Value, in string, now func() time.Time) error {
+	if out.Kind() == reflect.Pointer {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	expiry, err := time.Parse(time.RFC3339, in)
+	if err != nil {
+		return fmt.Errorf("invalid expiry timestamp: [%w]", err)
+	}
+	switch out.Type() {
+	case timeType:
+		out.Set(reflect.ValueOf(expiry))
+	case durationType:
+		out.SetInt(int64(expiry.Sub(now())))
+	default:
+		return fmt.Errorf("ttl encoding requires time.Time or time.Duration, got '%s'", out.Type())
+	}
+	return nil
+}
+
+// decodeBase decodes big.Int or integer from its representation in given base.
+func decodeBase(out reflect.Value, in string, base int) error {
+	if out.Kind() == reflect.Pointer {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	if out.Type() == bigIntType {
+		n, ok := new(big.Int).SetString(in, base)
+		if !ok {
+			return fmt.Errorf("invalid base %d integer '%s'", base, in)
+		}
+		out.Set(reflect.ValueOf(*n))
+		return nil
+	}
+	switch out.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(in, base, out.Type().Bits())
+		if err != nil {
+			return err
+		}
+		out.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(in, base, out.Type().Bits())
+		if err != nil {
+			return err
+		}
+		out.SetUint(n)
+	default:
+		return fmt.Errorf("base encoding requires big.Int or integer type, got '%s'", out.Type())
+	}
+	return nil
+}
+
+// decodePercent decodes integer from 0-100 range with optional '%' suffix.
+func decodePercent(dc *decodeContext, out reflect.Value, in string) error {
+	if err := decodeUndefined(dc, out, strings.TrimSuffix(in, percentSuffix)); err != nil {
+		return err
+	}
+	return checkPercent(out)
+}
+
+// decodeBoolTokens decodes bool, pointer or Option of bool from tokens of 'bool' encoding. Representations accepted
+// by strconv.ParseBool are accepted too.
+func decodeBoolTokens(dc *decodeContext, out reflect.Value, in string, params encParams) error {
+	if t := valueType(out.Type()); t.Kind() != reflect.Bool {
+		return fmt.Errorf("bool encoding requires bool type, got '%s'", t)
+	}
+	switch in {
+	case params.trueToken:
+		in = strconv.FormatBool(true)
+	case params.falseToken:
+		in = strconv.FormatBool(false)
+	default:
+		if _, err := strconv.ParseBool(in); err != nil {
+			return fmt.Errorf("invalid bool value '%s', expected '%s' or '%s'", in, params.trueToken, params.falseToken)
+		}
+	}
+	return decodeUndefined(dc, out, in)
+}
+
+func decodeWithEncoder(dc *decodeContext, out reflect.Value, in string, tag *parsedTag) error {
+	enc, params := dc.defaults.encoding(tag)
 	switch enc {
 	case encoder(undefined):
-		return decodeUndefined(out, in)
+		return decodeUndefined(dc, out, in)
 	case jsonEnc:
 		return decodeJson(out, in)
+	case binaryEnc:
+		return decodeBinary(out, in)
+	case hexEnc:
+		return decodeHex(out, in)
+	case ttlEnc:
+		return decodeTTL(out, in, dc.now)
+	case percentEnc, percentSuffixEnc:
+		return decodePercent(dc, out, in)
+	case baseEnc:
+		return decodeBase(out, in, params.base)
+	case boolEnc:
+		return decodeBoolTokens(dc, out, in, params)
+	case stringerEnc:
+		return decodeStringer(dc, out, in)
+	case labelSetEnc:
+		return decodeLabelSet(out, in)
+	case orderedMapEnc:
+		return decodeOrderedMap(dc, out, in)
+	case humanIntEnc:
+		// underscores are only visual separators of digits (e.g. "1_000")
+		return decodeBase(out, strings.ReplaceAll(in, "_", ""), 10)
 	}
 	return nil
 }
 
-func match(values map[string]string, tag *parsedTag) string {
-	if v, ok := values[tag.value]; ok {
-		return v
+func match(values map[string]string, tag *parsedTag) (string, bool) {
+	for _, key := range tag.lookupKeys() {
+		if v, ok := values[key]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func sourceValues(meta metav1.Object, src source) map[string]string {
+	switch src {
+	case annotation:
+		return meta.GetAnnotations()
+	case label:
+		return meta.GetLabels()
 	}
-	for _, alias := range tag.aliases {
-		if v, ok := values[alias]; ok {
-			return v
+	return nil
+}
+
+// resolve returns key and value referenced by tag. If the key is absent, aliases and tag fallbacks are checked in order.
+// With 'aliasfirst' aliases are checked before the key.
+func resolve(meta metav1.Object, tag *parsedTag) (keyRef, string, bool) {
+	values := sourceValues(meta, tag.source)
+	for _, key := range tag.lookupKeys() {
+		if v, ok := values[key]; ok {
+			return keyRef{tag.source, key}, v, true
 		}
 	}
-	return ""
+	for _, ref := range tag.fallbacks {
+		if v, ok := sourceValues(meta, ref.source)[ref.value]; ok {
+			return ref, v, true
+		}
+	}
+	return keyRef{tag.source, tag.value}, "", false
+}
+
+// lookup returns value of key referenced by tag. If the key is absent, tag fallbacks are checked in order and then
+// default value of tag is used.
+func lookup(meta metav1.Object, tag *parsedTag) string {
+	_, v, ok := resolve(meta, tag)
+	if !ok && tag.hasDefault {
+		return tag.defaultValue
+	}
+	return v
+}
+
+// shadowed checks if field indexed by fallback key has value present under key of higher priority.
+func shadowed(meta metav1.Object, info *fieldInfo) bool {
+	if info.fallback == 0 {
+		return false
+	}
+	if _, ok := match(sourceValues(meta, info.tag.source), &info.tag); ok {
+		return true
+	}
+	for _, ref := range info.tag.fallbacks[:info.fallback-1] {
+		if _, ok := sourceValues(meta, ref.source)[ref.value]; ok {
+			return true
+		}
+	}
+	return false
 }
 
 func decodeField(dc *decodeContext, tag *parsedTag, v reflect.Value) error {
@@ -327,15 +794,46 @@ func decodeField(dc *decodeContext, tag *parsedTag, v reflect.Value) error {
 
 	switch tag.source {
 	case name:
-		err = decodePrimitive(v, dc.meta.GetName())
+		if err = tag.checkDNS(dc.meta.GetName()); err == nil {
+			err = decodePrimitive(dc, v, dc.meta.GetName())
+		}
 	case namespace:
-		err = decodePrimitive(v, dc.meta.GetNamespace())
-	case label:
-		err = decodeWithEncoder(v, match(dc.meta.GetLabels(), tag), tag.enc)
-	case annotation:
-		err = decodeWithEncoder(v, match(dc.meta.GetAnnotations(), tag), tag.enc)
+		if err = tag.checkDNS(dc.meta.GetNamespace()); err == nil {
+			err = decodePrimitive(dc, v, dc.meta.GetNamespace())
+		}
+	case owner:
+		err = decodeOwner(v, dc.meta.GetOwnerReferences(), tag.value)
+	case labelPresence:
+		err = decodeLabelPresence(dc, v, tag)
+	case provided:
+		err = decodeProvided(dc, v, tag)
+	case allLabels:
+		err = decodeWholeMap(dc, v, dc.meta.GetLabels())
+	case allAnnotations:
+		err = decodeWholeMap(dc, v, dc.meta.GetAnnotations())
+	case creationTimestamp:
+		ts := dc.meta.GetCreationTimestamp()
+		err = decodeTimestamp(v, &ts)
+	case deletionTimestamp:
+		err = decodeTimestamp(v, dc.meta.GetDeletionTimestamp())
+	case finalizers:
+		err = decodeFinalizers(v, dc.meta.GetFinalizers())
+	case generation:
+		err = decodeGeneration(dc, v, dc.meta.GetGeneration())
+	case label, annotation:
+		if tag.isRange {
+			err = decodeRange(dc, v, tag)
+			break
+		}
+		val := lookup(dc.meta, tag)
+		if dc.caseInsensitive && tag.fold {
+			val = strings.ToLower(val)
+		}
+		if err = tag.checkDNS(val); err == nil {
+			err = decodeWithEncoder(dc, v, val, tag)
+		}
 	case source(undefined):
-		err = decodeCustom(v, dc.meta)
+		err = decodeCustom(v, originalMeta(dc.meta), tag.value)
 	}
 
 	if dc.accumulateFieldErrors && err != nil {
@@ -350,6 +848,70 @@ func decodeField(dc *decodeContext, tag *parsedTag, v reflect.Value) error {
 	return nil
 }
 
+// decodeLabelPresence decodes bool from label. Existing label with empty value means true.
+// Absent label is decoded as tag's absent value.
+func decodeLabelPresence(dc *decodeContext, out reflect.Value, tag *parsedTag) error {
+	if dereference(out).Kind() != reflect.Bool && !(out.Kind() == reflect.Pointer && out.Type().Elem().Kind() == reflect.Bool) {
+		return fmt.Errorf("labelpresence requires bool type, got '%s'", out.Type())
+	}
+	val, ok := dc.meta.GetLabels()[tag.value]
+	if !ok {
+		val = strconv.FormatBool(tag.absent)
+	} else if val == "" {
+		val = strconv.FormatBool(true)
+	}
+	return decodePrimitive(dc, out, val)
+}
+
+// decodeGeneration assigns generation to field of integer kind or pointer or Option of it.
+func decodeGeneration(dc *decodeContext, out reflect.Value, gen int64) error {
+	switch valueType(out.Type()).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return decodeUndefined(dc, out, strconv.FormatInt(gen, 10))
+	}
+	return fmt.Errorf("generation requires integer kind, got '%s'", out.Type())
+}
+
+// decodeTimestamp assigns ts to field of time.Time or metav1.Time type or pointer or Option of them.
+// Nil or zero ts sets zero value (nil pointer or unset Option).
+func decodeTimestamp(out reflect.Value, ts *metav1.Time) error {
+	var t metav1.Time
+	if ts != nil {
+		t = *ts
+	}
+	if isOption(out) {
+		if t.IsZero() {
+			out.Set(reflect.Zero(out.Type()))
+			return nil
+		}
+		if err := decodeTimestamp(asWritableValue(out.Field(valueFieldIndex)), &t); err != nil {
+			return err
+		}
+		asWritableValue(out.Field(isSetFieldIndex)).SetBool(true)
+		return nil
+	}
+	if out.Kind() == reflect.Pointer {
+		if t.IsZero() {
+			out.Set(reflect.Zero(out.Type()))
+			return nil
+		}
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	switch out.Type() {
+	case timeType:
+		out.Set(reflect.ValueOf(t.Time))
+	case metaTimeType:
+		out.Set(reflect.ValueOf(t))
+	default:
+		return fmt.Errorf("timestamp requires time.Time or metav1.Time type, got '%s'", out.Type())
+	}
+	return nil
+}
+
 func fieldByIndexWithAlloc(v reflect.Value, index []int) reflect.Value {
 	if len(index) == 1 {
 		return v.Field(index[0])
@@ -368,24 +930,158 @@ func fieldByIndexWithAlloc(v reflect.Value, index []int) reflect.Value {
 	return v
 }
 
+// fieldByIndexWithoutAlloc returns field of v located at index. If nil pointer is found on the path, new zero value
+// of the field is returned, so v is never modified.
+func fieldByIndexWithoutAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct {
+			if v.IsNil() {
+				t := v.Type()
+				for _, y := range index[i:] {
+					for t.Kind() == reflect.Pointer {
+						t = t.Elem()
+					}
+					t = t.Field(y).Type
+				}
+				return reflect.New(t).Elem()
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
 func decode(dc *decodeContext) error {
+	if dc.disallowUnknownKeys {
+		if err := checkUnknownKeys(dc); err != nil {
+			return err
+		}
+	}
+	if dc.cache.Flat && dc.filter == nil && dc.trace == nil && dc.touched == nil {
+		return decodeFlat(dc)
+	}
+	if dc.touched != nil {
+		start := len(*dc.touched)
+		defer func() { slices.Sort((*dc.touched)[start:]) }()
+	}
 	return iterate(dc, func(info *fieldInfo) error {
+		if err := dc.cancelled(); err != nil {
+			return err
+		}
 		if !dc.filter.Apply(info) {
 			return nil
 		}
-		if err := decodeField(dc, &info.tag, fieldByIndexWithAlloc(dc.root, info.path)); err != nil && !dc.accumulateFieldErrors {
+		v := fieldByIndexWithAlloc(dc.root, info.path)
+		n := len(dc.fieldErrors)
+		err := decodeField(dc, &info.tag, v)
+		if err != nil {
+			name := fieldName(dc.cache.CachedType, info.path)
+			err = withField(err, name)
+			recordFieldErrors(&dc.errorFields, dc.fieldErrors, n, name)
+		}
+		if dc.trace != nil {
+			traceField(dc, info, v, err)
+		}
+		if dc.touched != nil && err == nil && populated(dc.meta, &info.tag) {
+			*dc.touched = append(*dc.touched, fieldName(dc.cache.CachedType, info.path))
+		}
+		if err != nil && !dc.accumulateFieldErrors {
 			return err
 		}
 		return nil
 	})
 }
 
+// populated checks if field referenced by tag was decoded from key present in metadata.
+func populated(meta metav1.Object, tag *parsedTag) bool {
+	switch tag.source {
+	case labelPresence:
+		_, ok := meta.GetLabels()[tag.value]
+		return ok
+	case name, namespace, annotation, label, owner:
+		return present(meta, tag)
+	}
+	return false
+}
+
+// cancelled returns error of context passed to DecodeContext if it is done.
+func (dc *decodeContext) cancelled() error {
+	if dc.ctx == nil {
+		return nil
+	}
+	return dc.ctx.Err()
+}
+
+// decodeFlat decodes flat type (see typeCache.Flat) by looking up keys of its fields in metadata.
+func decodeFlat(dc *decodeContext) error {
+	annotations, labels := dc.meta.GetAnnotations(), dc.meta.GetLabels()
+	for i := range dc.cache.Fields {
+		if err := dc.cancelled(); err != nil {
+			return err
+		}
+		tag := &dc.cache.Fields[i].tag
+		values := annotations
+		if tag.source == label {
+			values = labels
+		}
+		if _, ok := values[tag.value]; !ok {
+			continue
+		}
+		n := len(dc.fieldErrors)
+		err := decodeField(dc, tag, dc.root.Field(dc.cache.Fields[i].path[0]))
+		recordFieldErrors(&dc.errorFields, dc.fieldErrors, n, fieldName(dc.cache.CachedType, dc.cache.Fields[i].path))
+		if err != nil && !dc.accumulateFieldErrors {
+			return err
+		}
+	}
+	if len(dc.fieldErrors) > 0 {
+		return &fieldError{message: "multiple fields errors encountered", fieldErrors: dc.fieldErrors, fields: dc.errorFields}
+	}
+	return nil
+}
+
+// checkUnknownKeys checks if all annotations with managed prefix are known to cached type.
+func checkUnknownKeys(dc *decodeContext) error {
+	for _, info := range dc.cache.WholeMapFastAccess {
+		if info.tag.source == allAnnotations {
+			return nil
+		}
+	}
+	keys := make([]string, 0, len(dc.meta.GetAnnotations()))
+	for k := range dc.meta.GetAnnotations() {
+		if _, known := dc.cache.AnnotationFastAccess[k]; !known && strings.HasPrefix(k, dc.unknownKeysPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if !dc.accumulateFieldErrors {
+			return fmt.Errorf("unknown annotation key '%s'", k)
+		}
+		dc.fieldErrors = append(dc.fieldErrors, field.Forbidden(field.NewPath("metadata").Child("annotations").Key(k), "unknown annotation key"))
+	}
+	return nil
+}
+
 func validate(dc *decodeContext) error {
 	return iterate(dc, func(info *fieldInfo) error {
+		if err := dc.cancelled(); err != nil {
+			return err
+		}
 		if !dc.filter.Apply(info) {
 			return nil
 		}
-		if err := validateField(dc, &info.tag, fieldByIndexWithAlloc(dc.root, info.path)); err != nil && !dc.accumulateFieldErrors {
+		var v reflect.Value
+		if dc.skipDefaultWorkload {
+			v = fieldByIndexWithoutAlloc(dc.root, info.path)
+		} else {
+			v = fieldByIndexWithAlloc(dc.root, info.path)
+		}
+		n := len(dc.fieldErrors)
+		err := validateField(dc, &info.tag, v)
+		recordFieldErrors(&dc.errorFields, dc.fieldErrors, n, fieldName(dc.cache.CachedType, info.path))
+		if err != nil && !dc.accumulateFieldErrors {
 			return err
 		}
 		return nil
@@ -393,37 +1089,106 @@ func validate(dc *decodeContext) error {
 }
 
 func iterate(dc *decodeContext, fn func(info *fieldInfo) error) error {
-	for _, info := range dc.cache.NameFastAccess {
-		if err := fn(&info); err != nil {
+	for i := range dc.cache.NameFastAccess {
+		info := &dc.cache.NameFastAccess[i]
+		if err := fn(info); err != nil {
 			return err
 		}
 	}
-	for _, info := range dc.cache.NamespaceFastAccess {
-		if err := fn(&info); err != nil {
+	for i := range dc.cache.NamespaceFastAccess {
+		info := &dc.cache.NamespaceFastAccess[i]
+		if err := fn(info); err != nil {
 			return err
 		}
 	}
+	// fields indexed by several present keys (aliases or keys of range) are visited once
+	visited := make([]bool, len(dc.cache.Fields))
 	for k := range dc.meta.GetAnnotations() {
-		for _, info := range dc.cache.AnnotationFastAccess[k] {
-			if err := fn(&info); err != nil {
+		infos := dc.cache.AnnotationFastAccess[k]
+		for i := range infos {
+			info := &infos[i]
+			if visited[info.index] || shadowed(dc.meta, info) {
+				continue
+			}
+			visited[info.index] = true
+			if err := fn(info); err != nil {
 				return err
 			}
 		}
 	}
 	for k := range dc.meta.GetLabels() {
-		for _, info := range dc.cache.LabelsFastAccess[k] {
-			if err := fn(&info); err != nil {
+		infos := dc.cache.LabelsFastAccess[k]
+		for i := range infos {
+			info := &infos[i]
+			if visited[info.index] || shadowed(dc.meta, info) {
+				continue
+			}
+			visited[info.index] = true
+			if err := fn(info); err != nil {
 				return err
 			}
 		}
 	}
-	for _, info := range dc.cache.CustomFieldsFastAccess {
-		if err := fn(&info); err != nil {
+	// fields with default are decoded even if their keys are absent
+	for i := range dc.cache.DefaultFastAccess {
+		info := &dc.cache.DefaultFastAccess[i]
+		if present(dc.meta, &info.tag) {
+			continue
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.LabelPresenceFastAccess {
+		info := &dc.cache.LabelPresenceFastAccess[i]
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.WholeMapFastAccess {
+		info := &dc.cache.WholeMapFastAccess[i]
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.TimestampFastAccess {
+		info := &dc.cache.TimestampFastAccess[i]
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.GenerationFastAccess {
+		info := &dc.cache.GenerationFastAccess[i]
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.FinalizersFastAccess {
+		info := &dc.cache.FinalizersFastAccess[i]
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.ProviderFastAccess {
+		info := &dc.cache.ProviderFastAccess[i]
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.OwnerFastAccess {
+		info := &dc.cache.OwnerFastAccess[i]
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	for i := range dc.cache.CustomFieldsFastAccess {
+		info := &dc.cache.CustomFieldsFastAccess[i]
+		if err := fn(info); err != nil {
 			return err
 		}
 	}
 	if len(dc.fieldErrors) > 0 {
-		return &decodeError{message: "multiple fields errors encountered", fieldErrors: dc.fieldErrors}
+		return &fieldError{message: "multiple fields errors encountered", fieldErrors: dc.fieldErrors, fields: dc.errorFields}
 	}
 	return nil
 }
@@ -434,31 +1199,55 @@ func iterate(dc *decodeContext, fn func(info *fieldInfo) error) error {
 func (dec *Decoder) Decode(meta metav1.Object, v any, options ...DecodeOption) error {
 
 	root := reflect.ValueOf(v)
-	var err error
 
 	if root.Kind() != reflect.Pointer {
 		return fmt.Errorf("required pointer to value")
 	}
 
-	cache := dec.cache.Load()
-	if cache == nil || cache.CachedType != root.Type() {
-		cache, err = newCache(root.Type())
-		if err != nil {
-			return err
+	if _, cached := dec.cache.Load(root.Type()); dec.strict && !cached {
+		if err := checkTags(root.Type()); err != nil {
+			return fmt.Errorf("invalid struct tags: %w", err)
 		}
-		dec.cache.Store(cache)
+	}
+
+	cache, err := loadCache(&dec.cache, root.Type(), dec.codecs)
+	if err != nil {
+		return err
 	}
 
 	dc := &decodeContext{
-		cache: cache,
-		root:  dereference(root),
-		meta:  meta,
+		cache:     cache,
+		root:      dereference(root),
+		meta:      meta,
+		now:       dec.clock(),
+		codecs:    dec.codecs,
+		stringers: dec.stringers,
+		defaults:  dec.defaults,
 	}
 
 	for _, opt := range options {
 		opt(dc)
 	}
 
+	if dc.keyFunc != nil {
+		dc.cache = dc.cache.withKeyFunc(dc.keyFunc)
+	}
+
+	if dc.normalizeKey != nil {
+		if dc.meta, err = newNormalizedMeta(meta, dc.normalizeKey); err != nil {
+			return err
+		}
+		dc.cache = dc.cache.withKeyFunc(func(_ source, key string) string { return dc.normalizeKey(key) })
+	} else {
+		dc.meta = newMetaView(meta)
+	}
+
+	if dc.validateKeys {
+		if err := dc.cache.validateKeys(); err != nil {
+			return fmt.Errorf("invalid keys in struct tags: %w", err)
+		}
+	}
+
 	if dc.performValidation {
 		if err := validate(dc); err != nil {
 			return fmt.Errorf("failed to validate fields: %w", err)
@@ -474,6 +1263,32 @@ func (dec *Decoder) Decode(meta metav1.Object, v any, options ...DecodeOption) e
 	return nil
 }
 
+// ValidateOnly checks if metadata is consistent with fields of v marked as immutable or setonce. Contrary to Decode
+// with Validate option, fields are not decoded and v is never modified (nil pointers to nested structs are not
+// allocated).
+func (dec *Decoder) ValidateOnly(meta metav1.Object, v any, options ...DecodeOption) error {
+	return dec.Decode(meta, v, append(options, Validate(true), func(dc *decodeContext) {
+		dc.skipDefaultWorkload = true
+	})...)
+}
+
+// DecodeContext is like Decode, but checks ctx before every decoded or validated field and returns ctx.Err()
+// when ctx is done. Fields decoded before cancellation are left in v.
+func (dec *Decoder) DecodeContext(ctx context.Context, meta metav1.Object, v any, options ...DecodeOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return dec.Decode(meta, v, append(options, func(dc *decodeContext) {
+		dc.ctx = ctx
+	})...)
+}
+
+// DecodeInto reads data from K8s object metadata and stores them in v. Contrary to Decode, decoded slices
+// and maps are merged into existing ones (see MergeCollections option).
+func (dec *Decoder) DecodeInto(meta metav1.Object, v any, options ...DecodeOption) error {
+	return dec.Decode(meta, v, append(options, MergeCollections())...)
+}
+
 func validateField(dc *decodeContext, tag *parsedTag, v reflect.Value) error {
 	var err error
 
@@ -483,6 +1298,11 @@ func validateField(dc *decodeContext, tag *parsedTag, v reflect.Value) error {
 		return nil
 	}
 
+	// absent annotation or label is not stored yet, so there is nothing to compare with
+	if (tag.source == annotation || tag.source == label) && !present(dc.meta, tag) {
+		return nil
+	}
+
 	// perform equality check
 	if tag.setOnce || tag.immutable {
 		cv := reflect.New(v.Type()).Elem()
@@ -503,16 +1323,76 @@ func validateField(dc *decodeContext, tag *parsedTag, v reflect.Value) error {
 	return nil
 }
 
+// equal checks if values are deeply equal. Options are equal if both are unset or both are set to equal values.
 func equal(v1, v2 reflect.Value) bool {
+	if isOption(v1) {
+		set1, set2 := v1.Field(isSetFieldIndex).Bool(), v2.Field(isSetFieldIndex).Bool()
+		if !set1 || !set2 {
+			return set1 == set2
+		}
+		return equal(optionValue(v1), optionValue(v2))
+	}
 	return reflect.DeepEqual(v1.Interface(), v2.Interface())
 }
 
+// optionValue returns readable value contained in Option.
+func optionValue(v reflect.Value) reflect.Value {
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	return asWritableValue(v.Field(valueFieldIndex))
+}
+
+// WithClock sets source of current time used by time-dependent decoders. By default time.Now is used.
+// It can be overridden for single Decode call with DecodeClock option.
+func (dec *Decoder) WithClock(now func() time.Time) *Decoder {
+	dec.now = now
+	return dec
+}
+
+// RegisterCodec sets function used to decode all values of type t with default encoding scheme. codec stores value
+// decoded from in into settable out. It takes precedence over encoding.TextUnmarshaler and default decoding of t,
+// including elements of slices, arrays and maps and values of pointers and options. Codecs should be registered
+// before the Decoder is used.
+func (dec *Decoder) RegisterCodec(t reflect.Type, codec func(out reflect.Value, in string) error) *Decoder {
+	if dec.codecs == nil {
+		dec.codecs = map[reflect.Type]func(out reflect.Value, in string) error{}
+	}
+	dec.codecs[t] = codec
+	return dec
+}
+
+func (dec *Decoder) clock() func() time.Time {
+	if dec.now != nil {
+		return dec.now
+	}
+	return time.Now
+}
+
 // NewDecoder returns new Decoder that reads data from meta.
 func NewDecoder() *Decoder {
 	return &Decoder{}
 }
 
+// NewDecoderWithDefaults returns new Decoder with behavior of fields set by defaults (e.g. DefaultEncoding).
+func NewDecoderWithDefaults(defaults ...DefaultOption) (*Decoder, error) {
+	d, err := newDefaults(defaults)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{defaults: d}, nil
+}
+
 // Unmarshal reads data from K8s object metadata using default Decoder.
 func Unmarshal(meta metav1.Object, v any, options ...DecodeOption) error {
 	return NewDecoder().Decode(meta, v, options...)
 }
+
+// MustUnmarshal is like Unmarshal but panics if an error occurs. It is intended for tests and initialization only.
+func MustUnmarshal(meta metav1.Object, v any, options ...DecodeOption) {
+	if err := Unmarshal(meta, v, options...); err != nil {
+		panic(err)
+	}
+}
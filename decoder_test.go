@@ -17,13 +17,20 @@ limitations under the License.
 package metaser
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // test decoding name + pointer + flatten namespace
@@ -72,6 +79,66 @@ func (ms *MyStruct4) UnmarshalFromMetadata(meta *metav1.ObjectMeta) error {
 	return nil
 }
 
+type MyItem struct {
+	Name  string
+	Count int
+}
+
+func (mi *MyItem) MarshalToMetadata(meta *metav1.ObjectMeta) error {
+	meta.Annotations["name"] = mi.Name
+	meta.Labels["count"] = strconv.Itoa(mi.Count)
+	return nil
+}
+
+func (mi *MyItem) UnmarshalFromMetadata(meta *metav1.ObjectMeta) error {
+	mi.Name = meta.Annotations["name"]
+	count, err := strconv.Atoi(meta.Labels["count"])
+	mi.Count = count
+	return err
+}
+
+type MyText struct {
+	S string
+}
+
+func (mt *MyText) MarshalText() ([]byte, error) {
+	return []byte(mt.S), nil
+}
+
+func (mt *MyText) UnmarshalText(text []byte) error {
+	mt.S = string(text)
+	return nil
+}
+
+type MyEnum int
+
+const (
+	MyEnumLow MyEnum = iota
+	MyEnumHigh
+)
+
+func (e MyEnum) MarshalText() ([]byte, error) {
+	switch e {
+	case MyEnumLow:
+		return []byte("low"), nil
+	case MyEnumHigh:
+		return []byte("high"), nil
+	}
+	return nil, fmt.Errorf("unknown enum value %d", int(e))
+}
+
+func (e *MyEnum) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*e = MyEnumLow
+	case "high":
+		*e = MyEnumHigh
+	default:
+		return fmt.Errorf("unknown enum value '%s'", text)
+	}
+	return nil
+}
+
 var _ = Describe("Decoder", func() {
 	Context("In case when name is present in metadata", func() {
 		When("decoding struct have string field with reference to name", func() {
@@ -434,10 +501,30 @@ var _ = Describe("Decoder", func() {
 				Expect(s.MyKey[2]).To(Equal(9))
 			})
 		})
-		When("struct have slice field with reference to annotation", func() {
-			It("should match annotation from metadata", func() {
+		When("struct have slice field with reference to empty annotation", func() {
+			It("should decode to empty slice", func() {
 				s := struct {
-					MyKey []int `k8s:"annotation:mykey"`
+					MyKey  []int    `k8s:"annotation:mykey"`
+					MyKey2 []string `k8s:"annotation:mykey2"`
+				}{}
+				m := &metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"mykey":  "",
+						"mykey2": "",
+					},
+				}
+				err := Unmarshal(m, &s)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.MyKey).ToNot(BeNil())
+				Expect(s.MyKey).To(BeEmpty())
+				Expect(s.MyKey2).ToNot(BeNil())
+				Expect(s.MyKey2).To(BeEmpty())
+			})
+		})
+		When("struct have array field with reference to empty annotation", func() {
+			It("should return error when array is not zero-length", func() {
+				s := struct {
+					MyKey [3]int `k8s:"annotation:mykey"`
 				}{}
 				m := &metav1.ObjectMeta{
 					Annotations: map[string]string{
@@ -447,6 +534,18 @@ var _ = Describe("Decoder", func() {
 				err := Unmarshal(m, &s)
 				Expect(err).To(HaveOccurred())
 			})
+			It("should succeed when array is zero-length", func() {
+				s := struct {
+					MyKey [0]int `k8s:"annotation:mykey"`
+				}{}
+				m := &metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"mykey": "",
+					},
+				}
+				err := Unmarshal(m, &s)
+				Expect(err).ToNot(HaveOccurred())
+			})
 		})
 		When("struct have map field with reference to annotation", func() {
 			It("should match annotation from metadata", func() {
@@ -469,7 +568,7 @@ var _ = Describe("Decoder", func() {
 		When("struct have struct field supporting TexUnmarshler with reference to annotation", func() {
 			It("should match annotation from metadata", func() {
 				s := struct {
-					MyKey MyStruct `k8s:"annotation:mykey"`
+					MyKey MyStruct `k8s:"annotation:mykey,in"`
 				}{}
 				m := &metav1.ObjectMeta{
 					Annotations: map[string]string{
@@ -484,7 +583,7 @@ var _ = Describe("Decoder", func() {
 		When("struct have struct field supporting TexUnmarshler with reference to annotation", func() {
 			It("should return error if TextUnmarshaler returns error", func() {
 				s := struct {
-					MyKey MyStruct2 `k8s:"annotation:mykey"`
+					MyKey MyStruct2 `k8s:"annotation:mykey,in"`
 				}{}
 				m := &metav1.ObjectMeta{
 					Annotations: map[string]string{
@@ -499,7 +598,7 @@ var _ = Describe("Decoder", func() {
 			It("should match annotation from metadata", func() {
 				inner := MyStruct{}
 				s := struct {
-					MyKey *MyStruct `k8s:"annotation:mykey"`
+					MyKey *MyStruct `k8s:"annotation:mykey,in"`
 				}{MyKey: &inner}
 				m := &metav1.ObjectMeta{
 					Annotations: map[string]string{
@@ -514,7 +613,7 @@ var _ = Describe("Decoder", func() {
 		When("struct have nil struct pointer field supporting TexUnmarshler with reference to annotation", func() {
 			It("should match annotation from metadata", func() {
 				s := struct {
-					MyKey *MyStruct `k8s:"annotation:mykey"`
+					MyKey *MyStruct `k8s:"annotation:mykey,in"`
 				}{}
 				m := &metav1.ObjectMeta{
 					Annotations: map[string]string{
@@ -541,6 +640,27 @@ var _ = Describe("Decoder", func() {
 				Expect(s.MyKey.A).To(Equal(12))
 			})
 		})
+		When("struct have pre-allocated struct pointer field with reference to json-encoded annotation", func() {
+			It("should preserve fields absent in json", func() {
+				type Pair struct {
+					A int
+					B string
+				}
+				pair := &Pair{B: "preset"}
+				s := struct {
+					MyKey *Pair `k8s:"annotation:mykey,enc:json"`
+				}{MyKey: pair}
+				m := &metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"mykey": `{ "A": 12 }`,
+					},
+				}
+				err := Unmarshal(m, &s)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.MyKey).To(BeIdenticalTo(pair))
+				Expect(*s.MyKey).To(Equal(Pair{A: 12, B: "preset"}))
+			})
+		})
 		When("struct have struct field with reference to json-encoded annotation", func() {
 			It("should match annotation from metadata", func() {
 				s := struct {
@@ -648,6 +768,33 @@ var _ = Describe("Decoder", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(s.MyKey.A).To(Equal(int64(10)))
 		})
+		It("should be deserialized into Option with CustomUnmarshaler", func() {
+			s := struct {
+				MyKey Option[MyStruct4] `k8s:"enc:custom"`
+			}{}
+			m := &metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"a-one": "1",
+					"a-two": "3",
+				},
+			}
+			err := Unmarshal(m, &s)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.MyKey.IsSet()).To(BeTrue())
+			Expect(s.MyKey.Get().A).To(Equal(int64(4)))
+		})
+		It("should leave Option unset when CustomUnmarshaler fails", func() {
+			s := struct {
+				MyKey Option[MyStruct4] `k8s:"enc:custom"`
+			}{}
+			m := &metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"a-one": "x",
+				},
+			}
+			Expect(Unmarshal(m, &s)).ToNot(Succeed())
+			Expect(s.MyKey.IsSet()).To(BeFalse())
+		})
 	})
 })
 
@@ -916,6 +1063,41 @@ var _ = Describe("Decoding Option struct", func() {
 	})
 })
 
+var _ = Describe("Decoding string Option", func() {
+	type A struct {
+		X Option[string]  `k8s:"annotation:x"`
+		Y Option[*string] `k8s:"label:y"`
+	}
+	It("should decode present empty value as Some(\"\")", func() {
+		v := A{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"x": ""}, Labels: map[string]string{"y": ""}}, &v)).To(Succeed())
+		Expect(v.X).To(Equal(Some("")))
+		Expect(v.Y.IsSet()).To(BeTrue())
+		Expect(*v.Y.Get()).To(BeEmpty())
+	})
+	It("should decode absent key as None", func() {
+		v := A{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &v)).To(Succeed())
+		Expect(v.X).To(Equal(None[string]()))
+		Expect(v.Y.IsSet()).To(BeFalse())
+	})
+	It("should decode non-empty value as Some", func() {
+		v := A{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"x": "x"}}, &v)).To(Succeed())
+		Expect(v.X).To(Equal(Some("x")))
+	})
+	It("should round-trip Some(\"\") and None", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&A{X: Some("")}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("x", ""))
+		Expect(m.Labels).ToNot(HaveKey("y"))
+		v := A{}
+		Expect(Unmarshal(m, &v)).To(Succeed())
+		Expect(v.X).To(Equal(Some("")))
+		Expect(v.Y.IsSet()).To(BeFalse())
+	})
+})
+
 var _ = Describe("Aliases tests", func() {
 	type A struct {
 		X *bool `k8s:"annotation:iks,aliases:myiks;alpha.mydomain.io/someiks"`
@@ -938,3 +1120,1551 @@ var _ = Describe("Aliases tests", func() {
 		})
 	})
 })
+
+var _ = Describe("Decoder with ValidateKeys enabled", func() {
+	It("should return error when label key is longer than 63 characters", func() {
+		s := struct {
+			MyKey string `k8s:"label:a123456789012345678901234567890123456789012345678901234567890123"`
+		}{}
+		m := &metav1.ObjectMeta{}
+		err := Unmarshal(m, &s, ValidateKeys())
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(HaveLen(1))
+	})
+	It("should return error when annotation key has invalid prefix", func() {
+		s := struct {
+			MyKey string `k8s:"annotation:-invalid_.domain/key"`
+		}{}
+		m := &metav1.ObjectMeta{}
+		err := Unmarshal(m, &s, ValidateKeys())
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(HaveLen(1))
+	})
+	It("should not lowercase label keys", func() {
+		s := struct {
+			Annotation string `k8s:"annotation:Example.com/key"`
+			Label      string `k8s:"label:Example.com/key"`
+			Presence   bool   `k8s:"labelpresence:Example.com/flag"`
+		}{}
+		err := Unmarshal(&metav1.ObjectMeta{}, &s, ValidateKeys())
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(HaveLen(2))
+		Expect(err).To(MatchError(ContainSubstring("metadata.label: Invalid value: \"Example.com/key\"")))
+	})
+	It("should return error when alias is invalid", func() {
+		s := struct {
+			MyKey string `k8s:"annotation:valid.io/key,aliases:in#valid"`
+		}{}
+		m := &metav1.ObjectMeta{}
+		err := Unmarshal(m, &s, ValidateKeys())
+		Expect(err).To(HaveOccurred())
+	})
+	It("should return no error when keys are valid", func() {
+		s := struct {
+			MyKey  string `k8s:"annotation:alpha.mydomain.io/key"`
+			MyKey2 string `k8s:"label:app.kubernetes.io/name"`
+		}{}
+		m := &metav1.ObjectMeta{Labels: map[string]string{"app.kubernetes.io/name": "test"}}
+		err := Unmarshal(m, &s, ValidateKeys())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.MyKey2).To(Equal("test"))
+	})
+	It("should not validate keys by default", func() {
+		s := struct {
+			MyKey string `k8s:"annotation:-invalid_.domain/key"`
+		}{}
+		m := &metav1.ObjectMeta{}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
+
+var _ = Describe("Custom-encoded slices", func() {
+	type S struct {
+		Items []MyItem `k8s:"enc:custom"`
+	}
+	It("should decode each element from its scoped keys", func() {
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"Items.item-0-name": "first",
+				"Items.item-1-name": "second",
+				"other":             "value",
+			},
+			Labels: map[string]string{
+				"Items.item-0-count": "1",
+				"Items.item-1-count": "2",
+			},
+		}
+		s := S{}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Items).To(Equal([]MyItem{{Name: "first", Count: 1}, {Name: "second", Count: 2}}))
+	})
+	It("should decode to nil slice when there are no scoped keys", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"other": "value"}}
+		s := S{Items: []MyItem{{Name: "stale"}}}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Items).To(BeNil())
+	})
+	It("should round-trip slice elements", func() {
+		in := S{Items: []MyItem{{Name: "a", Count: 1}, {Name: "b", Count: 2}, {Name: "c", Count: 3}}}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("Items.item-2-name", "c"))
+		Expect(m.Labels).To(HaveKeyWithValue("Items.item-2-count", "3"))
+
+		out := S{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(in))
+
+		in.Items = in.Items[:1]
+		err = Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).ToNot(HaveKey("Items.item-2-name"))
+		Expect(m.Labels).ToNot(HaveKey("Items.item-1-count"))
+
+		out = S{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(in))
+	})
+	It("should keep elements of different fields and foreign keys apart", func() {
+		type Two struct {
+			A []MyItem `k8s:"enc:custom"`
+			B []MyItem `k8s:"enc:custom"`
+		}
+		in := Two{A: []MyItem{{Name: "a0"}, {Name: "a1"}}, B: []MyItem{{Name: "b0"}}}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"item-7-color": "red"}}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("A.item-1-name", "a1"))
+		Expect(m.Annotations).To(HaveKeyWithValue("B.item-0-name", "b0"))
+		Expect(m.Annotations).To(HaveKeyWithValue("item-7-color", "red"))
+
+		out := Two{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.A).To(HaveLen(2))
+		Expect(out.B).To(HaveLen(1))
+		Expect(out.A[1].Name).To(Equal("a1"))
+		Expect(out.B[0].Name).To(Equal("b0"))
+
+		in.B = nil
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).ToNot(HaveKey("B.item-0-name"))
+		Expect(m.Annotations).To(HaveKeyWithValue("A.item-0-name", "a0"))
+		Expect(m.Annotations).To(HaveKeyWithValue("item-7-color", "red"))
+	})
+})
+
+var _ = Describe("Binary-encoded fields", func() {
+	type S struct {
+		T  time.Time  `k8s:"annotation:time,enc:binary"`
+		TP *time.Time `k8s:"annotation:timeptr,enc:binary,omitempty"`
+	}
+	It("should round-trip value through base64-encoded annotation", func() {
+		now := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+		in := S{T: now, TP: &now}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		bin, err := now.MarshalBinary()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("time", base64.StdEncoding.EncodeToString(bin)))
+		Expect(m.Annotations).To(HaveKeyWithValue("timeptr", base64.StdEncoding.EncodeToString(bin)))
+
+		out := S{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out.T.Equal(now)).To(BeTrue())
+		Expect(out.TP).ToNot(BeNil())
+		Expect(out.TP.Equal(now)).To(BeTrue())
+	})
+	It("should return error when annotation is not valid base64", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"time": "!!!"}}
+		err := Unmarshal(m, &S{})
+		Expect(err).To(HaveOccurred())
+	})
+	It("should return error when type does not implement BinaryUnmarshaler", func() {
+		s := struct {
+			V int `k8s:"annotation:value,enc:binary"`
+		}{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"value": "AQ=="}}
+		err := Unmarshal(m, &s)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("TTL-encoded fields", func() {
+	type S struct {
+		TTL    time.Duration `k8s:"annotation:ttl,enc:ttl"`
+		Expiry time.Time     `k8s:"annotation:expiry,enc:ttl"`
+	}
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	clock := func() time.Time { return now }
+	It("should encode duration as expiry timestamp", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{TTL: time.Hour, Expiry: now.Add(time.Minute)}, m, EncodeClock(clock))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("ttl", "2024-05-06T08:08:09Z"))
+		Expect(m.Annotations).To(HaveKeyWithValue("expiry", "2024-05-06T07:09:09Z"))
+	})
+	It("should decode remaining duration from expiry timestamp", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{TTL: time.Hour}, m, EncodeClock(clock))
+		Expect(err).ToNot(HaveOccurred())
+
+		s := S{}
+		later := func() time.Time { return now.Add(20 * time.Minute) }
+		err = Unmarshal(m, &s, DecodeClock(later))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.TTL).To(Equal(40 * time.Minute))
+		Expect(s.Expiry.IsZero()).To(BeTrue())
+	})
+	It("should return error for unsupported field type", func() {
+		s := struct {
+			V int `k8s:"annotation:ttl,enc:ttl"`
+		}{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"ttl": "2024-05-06T08:08:09Z"}}
+		Expect(Unmarshal(m, &s)).To(HaveOccurred())
+		Expect(Marshal(&s, m)).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Encoder and Decoder with clock", func() {
+	type S struct {
+		TTL time.Duration `k8s:"annotation:ttl,enc:ttl"`
+	}
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	It("should use clock set on Encoder and Decoder", func() {
+		enc := NewEncoder().WithClock(func() time.Time { return now })
+		dec := NewDecoder().WithClock(func() time.Time { return now.Add(time.Minute) })
+		m := &metav1.ObjectMeta{}
+		err := enc.Encode(&S{TTL: time.Hour}, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("ttl", "2024-05-06T08:08:09Z"))
+
+		s := S{}
+		err = dec.Decode(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.TTL).To(Equal(59 * time.Minute))
+	})
+	It("should prefer clock passed as option", func() {
+		dec := NewDecoder().WithClock(func() time.Time { return now.Add(time.Minute) })
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"ttl": "2024-05-06T08:08:09Z"}}
+		s := S{}
+		err := dec.Decode(m, &s, DecodeClock(func() time.Time { return now }))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.TTL).To(Equal(time.Hour))
+	})
+})
+
+var _ = Describe("Alternative sources", func() {
+	type S struct {
+		MyKey int `k8s:"annotation:foo|label:foo"`
+	}
+	It("should decode from label when annotation does not exist", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"foo": "2"}}
+		s := S{}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.MyKey).To(Equal(2))
+	})
+	It("should prefer annotation when both exist", func() {
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{"foo": "1"},
+			Labels:      map[string]string{"foo": "invalid"},
+		}
+		s := S{}
+		err := Unmarshal(m, &s, AccumulateFieldErrors())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.MyKey).To(Equal(1))
+	})
+	It("should encode only to the first source", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{MyKey: 3}, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("foo", "3"))
+		Expect(m.Labels).ToNot(HaveKey("foo"))
+	})
+	It("should return error for invalid alternative", func() {
+		s := struct {
+			MyKey int `k8s:"annotation:foo|name"`
+		}{}
+		err := Unmarshal(&metav1.ObjectMeta{}, &s)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Multi-level pointers", func() {
+	type S struct {
+		A **int           `k8s:"annotation:a"`
+		B **string        `k8s:"annotation:b"`
+		C Option[*bool]   `k8s:"annotation:c"`
+		D Option[*MyText] `k8s:"annotation:d"`
+		E Option[**int]   `k8s:"annotation:e"`
+	}
+	It("should allocate all pointer levels on decode", func() {
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{"a": "1", "b": "test", "c": "true", "d": "text", "e": "5"},
+		}
+		s := S{}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(**s.A).To(Equal(1))
+		Expect(**s.B).To(Equal("test"))
+		Expect(*s.C.Get()).To(BeTrue())
+		Expect(s.D.Get().S).To(Equal("text"))
+		Expect(**s.E.Get()).To(Equal(5))
+	})
+	It("should round-trip all pointer levels", func() {
+		i, str, b := 1, "test", false
+		pi, pstr := &i, &str
+		in := S{A: &pi, B: &pstr, C: Some(&b), D: Some(&MyText{S: "text"}), E: Some(&pi)}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(Equal(map[string]string{"a": "1", "b": "test", "c": "false", "d": "text", "e": "1"}))
+
+		out := S{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(in))
+	})
+})
+
+var _ = Describe("Percentage fields", func() {
+	type S struct {
+		Plain  int   `k8s:"annotation:plain,percentint"`
+		Suffix uint8 `k8s:"annotation:suffix,percentint:suffix"`
+	}
+	It("should round-trip in-range values", func() {
+		in := S{Plain: 0, Suffix: 100}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("plain", "0"))
+		Expect(m.Annotations).To(HaveKeyWithValue("suffix", "100%"))
+
+		out := S{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(in))
+	})
+	It("should accept suffix on decode", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"plain": "25%", "suffix": "50"}}
+		out := S{}
+		err := Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(S{Plain: 25, Suffix: 50}))
+	})
+	It("should return error for out-of-range values", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"plain": "-1", "suffix": "101%"}}
+		err := Unmarshal(m, &S{}, AccumulateFieldErrors())
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(HaveLen(2))
+
+		err = Marshal(&S{Plain: 101}, &metav1.ObjectMeta{})
+		Expect(err).To(HaveOccurred())
+		err = Marshal(&S{Suffix: 200}, &metav1.ObjectMeta{})
+		Expect(err).To(HaveOccurred())
+	})
+	It("should return error for invalid tags", func() {
+		s := struct {
+			V int `k8s:"annotation:v,percentint,enc:json"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(HaveOccurred())
+		s2 := struct {
+			V int `k8s:"annotation:v,percentint:prefix"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s2)).To(HaveOccurred())
+	})
+	It("should support option fields", func() {
+		type O struct {
+			Set   Option[int]   `k8s:"annotation:set,percentint:suffix"`
+			Unset Option[uint8] `k8s:"annotation:unset,percentint"`
+		}
+		in := O{Set: Some(40)}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"set": "40%"}))
+		out := O{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+
+		Expect(Marshal(&O{Set: Some(101)}, &metav1.ObjectMeta{})).To(MatchError(ContainSubstring("out of percentage range")))
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"unset": "101%"}}, &O{})
+		Expect(err).To(MatchError(ContainSubstring("out of percentage range")))
+	})
+})
+
+var _ = Describe("Owner references", func() {
+	refs := []metav1.OwnerReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "cm", UID: "1"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "deploy", UID: "2"},
+	}
+	It("should decode first owner reference into single field", func() {
+		s := struct {
+			Owner metav1.OwnerReference `k8s:"owner"`
+		}{}
+		err := Unmarshal(&metav1.ObjectMeta{OwnerReferences: refs}, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Owner).To(Equal(refs[0]))
+	})
+	It("should decode owner reference of matching kind into subset struct", func() {
+		type Ref struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		}
+		s := struct {
+			Owner *Ref `k8s:"owner:Deployment"`
+		}{}
+		err := Unmarshal(&metav1.ObjectMeta{OwnerReferences: refs}, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Owner).To(Equal(&Ref{Kind: "Deployment", Name: "deploy"}))
+	})
+	It("should decode all owner references into slice field", func() {
+		s := struct {
+			Owners []metav1.OwnerReference `k8s:"owner"`
+		}{}
+		err := Unmarshal(&metav1.ObjectMeta{OwnerReferences: refs}, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Owners).To(Equal(refs))
+	})
+	It("should replace owner reference with the same UID on encode", func() {
+		s := struct {
+			Owner metav1.OwnerReference `k8s:"owner"`
+		}{Owner: metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "renamed", UID: "1"}}
+		m := &metav1.ObjectMeta{OwnerReferences: append([]metav1.OwnerReference(nil), refs...)}
+		err := Marshal(&s, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.OwnerReferences).To(ConsistOf(refs[1], s.Owner))
+	})
+	It("should round-trip slice of owner references of given kind", func() {
+		type S struct {
+			Owners []metav1.OwnerReference `k8s:"owner:Deployment"`
+		}
+		in := S{Owners: []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "a", UID: "3"},
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "b", UID: "4"},
+		}}
+		m := &metav1.ObjectMeta{OwnerReferences: append([]metav1.OwnerReference(nil), refs...)}
+		err := Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.OwnerReferences).To(HaveLen(3))
+		Expect(m.OwnerReferences).To(ContainElement(refs[0]))
+
+		out := S{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(in))
+	})
+})
+
+var _ = Describe("Decoder with MergeCollections enabled", func() {
+	type S struct {
+		Slice []int          `k8s:"annotation:slice"`
+		Map   map[string]int `k8s:"annotation:map"`
+	}
+	m := &metav1.ObjectMeta{
+		Annotations: map[string]string{
+			"slice": "3,4",
+			"map":   "b:2,c:3",
+		},
+	}
+	It("should append to slice and merge into map", func() {
+		s := S{Slice: []int{1, 2}, Map: map[string]int{"a": 1, "b": 0}}
+		err := Unmarshal(m, &s, MergeCollections())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Slice).To(Equal([]int{1, 2, 3, 4}))
+		Expect(s.Map).To(Equal(map[string]int{"a": 1, "b": 2, "c": 3}))
+	})
+	It("should merge with DecodeInto", func() {
+		s := S{Slice: []int{1}, Map: map[string]int{"a": 1}}
+		err := NewDecoder().DecodeInto(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Slice).To(Equal([]int{1, 3, 4}))
+		Expect(s.Map).To(Equal(map[string]int{"a": 1, "b": 2, "c": 3}))
+	})
+	It("should allocate nil collections", func() {
+		s := S{}
+		err := Unmarshal(m, &s, MergeCollections())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Slice).To(Equal([]int{3, 4}))
+		Expect(s.Map).To(Equal(map[string]int{"b": 2, "c": 3}))
+	})
+	It("should merge field with present key and alias once", func() {
+		type A struct {
+			Slice []int `k8s:"annotation:slice,aliases:old-slice"`
+		}
+		s := A{Slice: []int{0}}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"slice": "1", "old-slice": "2"}}
+		Expect(NewDecoder().DecodeInto(m, &s)).To(Succeed())
+		Expect(s.Slice).To(Equal([]int{0, 1}))
+	})
+	It("should overwrite collections by default", func() {
+		s := S{Slice: []int{1, 2}, Map: map[string]int{"a": 1}}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Slice).To(Equal([]int{3, 4}))
+		Expect(s.Map).To(Equal(map[string]int{"b": 2, "c": 3}))
+	})
+})
+
+var _ = Describe("Label presence fields", func() {
+	type S struct {
+		Enabled  bool `k8s:"labelpresence:enabled"`
+		Disabled bool `k8s:"labelpresence:disabled,absent:true"`
+	}
+	It("should decode label existence", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"enabled": "", "disabled": "false"}}
+		s := S{}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Enabled).To(BeTrue())
+		Expect(s.Disabled).To(BeFalse())
+	})
+	It("should decode absent value when label does not exist", func() {
+		s := S{Enabled: true}
+		err := Unmarshal(&metav1.ObjectMeta{}, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Enabled).To(BeFalse())
+		Expect(s.Disabled).To(BeTrue())
+	})
+	It("should round-trip values", func() {
+		for _, in := range []S{{true, true}, {true, false}, {false, true}, {false, false}} {
+			m := &metav1.ObjectMeta{Labels: map[string]string{"enabled": "", "disabled": ""}}
+			err := Marshal(&in, m)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Labels).To(HaveLen(btoi(in.Enabled) + btoi(!in.Disabled)))
+
+			out := S{}
+			err = Unmarshal(m, &out)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(Equal(in))
+		}
+	})
+	It("should return error for invalid tags and types", func() {
+		s := struct {
+			V bool `k8s:"label:v,absent:true"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(HaveOccurred())
+		s2 := struct {
+			V int `k8s:"labelpresence:v"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s2)).To(HaveOccurred())
+	})
+})
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var _ = Describe("Inline structs with prefix", func() {
+	type Net struct {
+		Address string `k8s:"annotation:address"`
+		Port    int    `k8s:"label:port,aliases:p"`
+	}
+	type S struct {
+		Internal Net  `k8s:"inline,prefix:internal-"`
+		External *Net `k8s:"inline,prefix:external-"`
+	}
+	It("should decode two copies of the same struct using different prefixes", func() {
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{"internal-address": "10.0.0.1", "external-address": "1.2.3.4"},
+			Labels:      map[string]string{"internal-port": "80", "external-p": "443"},
+		}
+		s := S{}
+		err := Unmarshal(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Internal).To(Equal(Net{Address: "10.0.0.1", Port: 80}))
+		Expect(s.External).To(Equal(&Net{Address: "1.2.3.4", Port: 443}))
+	})
+	It("should round-trip two copies of the same struct using different prefixes", func() {
+		in := S{Internal: Net{Address: "a", Port: 1}, External: &Net{Address: "b", Port: 2}}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(Equal(map[string]string{"internal-address": "a", "external-address": "b"}))
+		Expect(m.Labels).To(Equal(map[string]string{"internal-port": "1", "external-port": "2"}))
+
+		out := S{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(in))
+	})
+	It("should compose prefixes of nested inline structs", func() {
+		type B struct {
+			Key string `k8s:"annotation:key"`
+		}
+		type A struct {
+			B B `k8s:"inline,prefix:b."`
+		}
+		type Root struct {
+			A A `k8s:"inline,prefix:a."`
+		}
+		in := Root{A: A{B: B{Key: "value"}}}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&in, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(Equal(map[string]string{"a.b.key": "value"}))
+
+		out := Root{}
+		err = Unmarshal(m, &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(in))
+	})
+	It("should return error when prefix is used without inline", func() {
+		s := struct {
+			V string `k8s:"annotation:v,prefix:a-"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("TextMarshaler asymmetry detection", func() {
+	It("should return error for marshal-only type tagged inout", func() {
+		s := struct {
+			MyKey MyStruct6 `k8s:"annotation:test"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(HaveOccurred())
+		Expect(Marshal(&s, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+	It("should accept marshal-only type tagged out", func() {
+		s := struct {
+			MyKey *MyStruct6 `k8s:"annotation:test,out"`
+		}{MyKey: &MyStruct6{A: []int{1}}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&s, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("test", "vals-1;"))
+		Expect(Unmarshal(m, &s)).To(Succeed())
+	})
+	It("should return error for unmarshal-only type tagged out", func() {
+		s := struct {
+			MyKey MyStruct `k8s:"label:test,out"`
+		}{}
+		Expect(Marshal(&s, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+	It("should accept types implementing both interfaces", func() {
+		s := struct {
+			MyKey MyText `k8s:"annotation:test"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(Succeed())
+	})
+	It("should accept marshal-only type with registered codecs", func() {
+		type S struct {
+			MyKey MyStruct6  `k8s:"annotation:test"`
+			Ptr   *MyStruct6 `k8s:"annotation:ptr"`
+		}
+		enc := NewEncoder().RegisterCodec(reflect.TypeOf(MyStruct6{}), func(in reflect.Value) (string, error) {
+			return fmt.Sprint(in.Interface().(MyStruct6).A), nil
+		})
+		dec := NewDecoder().RegisterCodec(reflect.TypeOf(MyStruct6{}), func(out reflect.Value, in string) error {
+			out.Set(reflect.ValueOf(MyStruct6{A: []int{len(in)}}))
+			return nil
+		})
+		m := &metav1.ObjectMeta{}
+		Expect(enc.Encode(&S{MyKey: MyStruct6{A: []int{1}}}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("test", "[1]"))
+		out := S{}
+		Expect(dec.Decode(m, &out)).To(Succeed())
+		Expect(out.MyKey).To(Equal(MyStruct6{A: []int{3}}))
+		Expect(Unmarshal(m, &out)).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("DNS-1123 constrained fields", func() {
+	type dnsStruct struct {
+		Name  string `k8s:"name,dns1123subdomain"`
+		Label string `k8s:"label:app,dns1123label"`
+	}
+
+	It("should decode valid DNS names", func() {
+		s := dnsStruct{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Name: "my.app-1", Labels: map[string]string{"app": "web-1"}}, &s)).To(Succeed())
+		Expect(s).To(Equal(dnsStruct{Name: "my.app-1", Label: "web-1"}))
+	})
+	It("should return error for invalid DNS names on decode", func() {
+		s := dnsStruct{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Name: "My_App"}, &s)).To(MatchError(ContainSubstring("invalid DNS-1123 value 'My_App'")))
+		Expect(Unmarshal(&metav1.ObjectMeta{Labels: map[string]string{"app": "web.1"}}, &s)).To(HaveOccurred())
+	})
+	It("should encode valid DNS names", func() {
+		meta := &metav1.ObjectMeta{}
+		Expect(Marshal(&dnsStruct{Name: "my.app-1", Label: "web-1"}, meta)).To(Succeed())
+		Expect(meta.Name).To(Equal("my.app-1"))
+		Expect(meta.Labels).To(HaveKeyWithValue("app", "web-1"))
+	})
+	It("should return error for invalid DNS names on encode", func() {
+		meta := &metav1.ObjectMeta{}
+		Expect(Marshal(&dnsStruct{Name: "-invalid"}, meta)).To(HaveOccurred())
+		Expect(meta.Name).To(BeEmpty())
+		meta = &metav1.ObjectMeta{}
+		Expect(Marshal(&dnsStruct{Label: "a.b"}, meta)).To(HaveOccurred())
+		Expect(meta.Labels).NotTo(HaveKey("app"))
+	})
+	It("should return error when used with unsupported source", func() {
+		s := struct {
+			V bool `k8s:"labelpresence:v,dns1123label"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Decoder with WithKeyNormalizer enabled", func() {
+	normalize := func(key string) string {
+		return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+	}
+	type S struct {
+		MaxReplicas int    `k8s:"annotation:example.com/maxReplicas"`
+		LogLevel    string `k8s:"label:logLevel,aliases:verbosity"`
+		Enabled     bool   `k8s:"labelpresence:featureEnabled"`
+	}
+
+	It("should decode kebab-case and snake_case metadata into camelCase tags", func() {
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{"example.com/max-replicas": "5"},
+			Labels:      map[string]string{"log_level": "debug", "feature-enabled": ""},
+		}
+		s := S{}
+		Expect(Unmarshal(m, &s, WithKeyNormalizer(normalize))).To(Succeed())
+		Expect(s).To(Equal(S{MaxReplicas: 5, LogLevel: "debug", Enabled: true}))
+		Expect(m.Annotations).To(HaveKey("example.com/max-replicas"))
+	})
+	It("should not match mixed conventions without normalizer", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/max-replicas": "5"}}
+		s := S{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.MaxReplicas).To(BeZero())
+	})
+	It("should return error for ambiguous metadata keys", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"log-level": "debug", "log_level": "info"}}
+		Expect(Unmarshal(m, &S{}, WithKeyNormalizer(normalize))).To(MatchError(ContainSubstring("ambiguous label keys")))
+	})
+})
+
+var _ = Describe("Validating immutable Option fields", func() {
+	type S struct {
+		V Option[int] `k8s:"annotation:v,immutable"`
+	}
+
+	It("should accept option with equal value", func() {
+		s := S{V: Some(1)}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"v": "1"}}, &s, Validate(true))).To(Succeed())
+	})
+	It("should return error when only value differs", func() {
+		s := S{V: Some(1)}
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"v": "2"}}, &s, Validate(true))
+		Expect(err).To(MatchError(ContainSubstring("field is immutable")))
+	})
+	It("should return error when unset option becomes set", func() {
+		s := S{V: Option[int]{value: 2}}
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"v": "2"}}, &s, Validate(true))
+		Expect(err).To(MatchError(ContainSubstring("field is immutable")))
+	})
+})
+
+var _ = Describe("MustUnmarshal", func() {
+	It("should not panic on success", func() {
+		s := struct {
+			A int `k8s:"annotation:a"`
+		}{}
+		Expect(func() { MustUnmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"a": "1"}}, &s) }).ToNot(Panic())
+		Expect(s.A).To(Equal(1))
+	})
+	It("should panic on invalid tag", func() {
+		s := struct {
+			A int `k8s:"annotation:a,unknown:x"`
+		}{}
+		Expect(func() { MustUnmarshal(&metav1.ObjectMeta{}, &s) }).To(Panic())
+	})
+})
+
+var _ = Describe("Creation timestamp fields", func() {
+	type S struct {
+		Time     time.Time   `k8s:"creationtimestamp"`
+		MetaTime metav1.Time `k8s:"creationtimestamp"`
+		Ptr      *time.Time  `k8s:"creationtimestamp"`
+	}
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	It("should decode creation timestamp into time fields", func() {
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}, &s)).To(Succeed())
+		Expect(s.Time).To(Equal(created))
+		Expect(s.MetaTime).To(Equal(metav1.NewTime(created)))
+		Expect(s.Ptr).ToNot(BeNil())
+		Expect(*s.Ptr).To(Equal(created))
+	})
+	It("should decode zero creation timestamp", func() {
+		s := S{Ptr: &created}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(Succeed())
+		Expect(s.Time.IsZero()).To(BeTrue())
+		Expect(s.Ptr).To(BeNil())
+	})
+	It("should ignore creation timestamp fields during encoding", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Time: created}, m)).To(Succeed())
+		Expect(m.CreationTimestamp.IsZero()).To(BeTrue())
+	})
+	It("should return error for unsupported type", func() {
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &struct {
+			V string `k8s:"creationtimestamp"`
+		}{})).To(MatchError(ContainSubstring("timestamp requires time.Time or metav1.Time type")))
+	})
+})
+
+var _ = Describe("Decoding unsupported types", func() {
+	type Inner struct {
+		Ch chan int `k8s:"annotation:ch"`
+	}
+	type S struct {
+		Inner Inner `k8s:"inline"`
+	}
+
+	It("should return UnsupportedTypeError", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"ch": "1"}}, &S{})
+		Expect(errors.Is(err, ErrUnsupportedType)).To(BeTrue())
+		var ute *UnsupportedTypeError
+		Expect(errors.As(err, &ute)).To(BeTrue())
+		Expect(ute.Type).To(Equal(reflect.TypeOf(make(chan int))))
+		Expect(ute.Field).To(Equal("Inner.Ch"))
+	})
+	It("should wrap ErrUnsupportedType for unknown encoding", func() {
+		err := Unmarshal(&metav1.ObjectMeta{}, &struct {
+			V int `k8s:"annotation:v,enc:yaml"`
+		}{})
+		Expect(errors.Is(err, ErrUnsupportedType)).To(BeTrue())
+	})
+})
+
+type namedPort int32
+type namedTags []string
+type namedMeta map[string]string
+type namedKey string
+type namedRatio float64
+
+var _ = Describe("Named types", func() {
+	type S struct {
+		Port  namedPort              `k8s:"annotation:port"`
+		Tags  namedTags              `k8s:"annotation:tags"`
+		Meta  namedMeta              `k8s:"annotation:meta"`
+		Keys  map[namedKey]namedPort `k8s:"annotation:keys"`
+		Ints  map[int]bool           `k8s:"label:ints"`
+		Ratio *namedRatio            `k8s:"label:ratio"`
+		Fixed [2]namedPort           `k8s:"label:fixed"`
+	}
+
+	It("should round-trip fields of named types", func() {
+		ratio := namedRatio(0.5)
+		in := S{
+			Port:  8080,
+			Tags:  namedTags{"a", "b"},
+			Meta:  namedMeta{"k": "v"},
+			Keys:  map[namedKey]namedPort{"http": 80},
+			Ints:  map[int]bool{1: true},
+			Ratio: &ratio,
+			Fixed: [2]namedPort{1, 2},
+		}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"port": "8080", "tags": "a,b", "meta": "k:v", "keys": "http:80"}))
+		Expect(m.Labels).To(Equal(map[string]string{"ints": "1:true", "ratio": "0.5", "fixed": "1,2"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return error for invalid map key", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Labels: map[string]string{"ints": "x:true"}}, &S{})
+		Expect(err).To(MatchError(ContainSubstring("unable to decode map key 'x'")))
+	})
+})
+
+var _ = Describe("Encoding and decoding metav1.Duration", func() {
+	type S struct {
+		Timeout  metav1.Duration         `k8s:"annotation:timeout"`
+		Interval *metav1.Duration        `k8s:"annotation:interval,omitempty"`
+		Grace    Option[metav1.Duration] `k8s:"label:grace"`
+	}
+
+	It("should round-trip values, pointers and options", func() {
+		in := S{
+			Timeout:  metav1.Duration{Duration: 30 * time.Second},
+			Interval: &metav1.Duration{Duration: 90 * time.Minute},
+			Grace:    Some(metav1.Duration{Duration: 1500 * time.Millisecond}),
+		}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"timeout": "30s", "interval": "1h30m0s"}))
+		Expect(m.Labels).To(Equal(map[string]string{"grace": "1.5s"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return error for invalid duration", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"timeout": "30"}}, &S{})
+		Expect(err).To(MatchError(ContainSubstring("missing unit in duration")))
+	})
+})
+
+var _ = Describe("Decoder with DisallowUnknownKeys enabled", func() {
+	type S struct {
+		Replicas int    `k8s:"annotation:example.com/replicas"`
+		Owner    string `k8s:"annotation:example.com/owner,aliases:example.com/team"`
+	}
+
+	It("should return error for unknown key with managed prefix", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/replicas": "1", "example.com/replcias": "2"}}
+		err := Unmarshal(m, &S{}, DisallowUnknownKeys("example.com/"))
+		Expect(err).To(MatchError(ContainSubstring("unknown annotation key 'example.com/replcias'")))
+	})
+	It("should ignore unknown keys with other prefix and known aliases", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/team": "a", "other.io/x": "y"}}
+		s := S{}
+		Expect(Unmarshal(m, &s, DisallowUnknownKeys("example.com/"))).To(Succeed())
+		Expect(s.Owner).To(Equal("a"))
+	})
+	It("should accumulate all unknown keys", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/a": "1", "example.com/b": "2"}}
+		err := Unmarshal(m, &S{}, DisallowUnknownKeys("example.com/"), AccumulateFieldErrors())
+		Expect(GetErrorList(err)).To(HaveLen(2))
+		Expect(GetErrorList(err)[0].Field).To(Equal("metadata.annotations[example.com/a]"))
+	})
+	It("should allow any key when type contains whole annotations map", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/a": "1"}}
+		Expect(Unmarshal(m, &struct {
+			All map[string]string `k8s:"annotations"`
+		}{}, DisallowUnknownKeys("example.com/"))).To(Succeed())
+	})
+})
+
+var _ = Describe("Decoder.ValidateOnly", func() {
+	type Inner struct {
+		Zone string `k8s:"annotation:zone,immutable"`
+	}
+	type S struct {
+		ID      string      `k8s:"annotation:id,setonce"`
+		Version int         `k8s:"annotation:version"`
+		Inner   *Inner      `k8s:"inline"`
+		Tier    Option[int] `k8s:"label:tier,immutable"`
+	}
+
+	It("should not modify value when metadata is consistent", func() {
+		s := S{ID: "a", Version: 1, Tier: Some(2)}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"id": "a", "version": "5"}, Labels: map[string]string{"tier": "2"}}
+		Expect(NewDecoder().ValidateOnly(m, &s)).To(Succeed())
+		Expect(s).To(Equal(S{ID: "a", Version: 1, Tier: Some(2)}))
+	})
+	It("should not allocate nil nested structs", func() {
+		s := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"zone": "eu"}}
+		err := NewDecoder().ValidateOnly(m, &s)
+		Expect(err).To(MatchError(ContainSubstring("field is immutable")))
+		Expect(s.Inner).To(BeNil())
+	})
+	It("should report all inconsistent fields without modifying value", func() {
+		s := S{ID: "a", Tier: Some(1)}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"id": "b"}, Labels: map[string]string{"tier": "3"}}
+		err := NewDecoder().ValidateOnly(m, &s, AccumulateFieldErrors())
+		Expect(GetErrorList(err)).To(HaveLen(2))
+		Expect(s).To(Equal(S{ID: "a", Tier: Some(1)}))
+	})
+})
+
+var _ = Describe("GetErrorList", func() {
+	type S struct {
+		A int `k8s:"annotation:a"`
+		B int `k8s:"label:b"`
+	}
+	invalid := &metav1.ObjectMeta{Annotations: map[string]string{"a": "x"}, Labels: map[string]string{"b": "y"}}
+
+	It("should find decode errors in wrapped errors", func() {
+		err := fmt.Errorf("reconcile failed: %w", Unmarshal(invalid, &S{}, AccumulateFieldErrors()))
+		Expect(GetErrorList(err)).To(HaveLen(2))
+	})
+	It("should find encode errors in wrapped errors", func() {
+		err := Marshal(&struct {
+			A uintptr `k8s:"annotation:a"`
+		}{}, &metav1.ObjectMeta{}, AccumulateEncodeFieldErrors())
+		Expect(GetErrorList(fmt.Errorf("outer: %w", err))).To(HaveLen(1))
+	})
+	It("should concatenate lists of joined errors", func() {
+		decodeErr := Unmarshal(invalid, &S{}, AccumulateFieldErrors())
+		encodeErr := Marshal(&struct {
+			A uintptr `k8s:"annotation:a"`
+		}{}, &metav1.ObjectMeta{}, AccumulateEncodeFieldErrors())
+		Expect(GetErrorList(errors.Join(decodeErr, fmt.Errorf("wrapped: %w", encodeErr)))).To(HaveLen(3))
+	})
+	It("should return nil for errors without field errors", func() {
+		Expect(GetErrorList(nil)).To(BeNil())
+		Expect(GetErrorList(errors.New("plain"))).To(BeNil())
+	})
+})
+
+var _ = Describe("Decoder with CaseInsensitive enabled", func() {
+	type Mode string
+	type S struct {
+		Mode   Mode           `k8s:"annotation:mode,fold"`
+		Tier   Option[string] `k8s:"label:tier,fold"`
+		Region *string        `k8s:"annotation:region,fold"`
+		Owner  string         `k8s:"annotation:owner"`
+	}
+	m := &metav1.ObjectMeta{
+		Annotations: map[string]string{"mode": "Enabled", "region": "EU-West", "owner": "Alice"},
+		Labels:      map[string]string{"tier": "GOLD"},
+	}
+
+	It("should lowercase values of folded fields", func() {
+		s := S{}
+		Expect(Unmarshal(m, &s, CaseInsensitive())).To(Succeed())
+		Expect(s.Mode).To(Equal(Mode("enabled")))
+		Expect(s.Tier).To(Equal(Some("gold")))
+		Expect(*s.Region).To(Equal("eu-west"))
+	})
+	It("should not modify values of fields without fold", func() {
+		s := S{}
+		Expect(Unmarshal(m, &s, CaseInsensitive())).To(Succeed())
+		Expect(s.Owner).To(Equal("Alice"))
+	})
+	It("should not modify values without CaseInsensitive option", func() {
+		s := S{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Mode).To(Equal(Mode("Enabled")))
+	})
+	It("should reject fold on non-string fields", func() {
+		err := Unmarshal(m, &struct {
+			A int `k8s:"annotation:a,fold"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("'fold' requires string kind")))
+	})
+	It("should reject fold with encoding", func() {
+		err := Unmarshal(m, &struct {
+			A string `k8s:"annotation:a,enc:json,fold"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("'fold' can be used only")))
+	})
+})
+
+var _ = Describe("Map with keys implementing encoding.TextUnmarshaler", func() {
+	type S struct {
+		Limits map[MyEnum]int `k8s:"annotation:limits"`
+	}
+
+	It("should decode keys using UnmarshalText", func() {
+		s := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"limits": "high:10,low:1"}}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Limits).To(Equal(map[MyEnum]int{MyEnumLow: 1, MyEnumHigh: 10}))
+	})
+	It("should round-trip typed keys", func() {
+		in := S{Limits: map[MyEnum]int{MyEnumLow: 3, MyEnumHigh: 7}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("limits", "high:7,low:3"))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return an error for unknown key", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"limits": "medium:5"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("unable to decode map key 'medium'")))
+	})
+})
+
+var _ = Describe("Validation of immutable fields with absent keys", func() {
+	type S struct {
+		Zone   string      `k8s:"annotation:zone,immutable"`
+		Region string      `k8s:"annotation:region|label:region,immutable"`
+		Tier   Option[int] `k8s:"label:tier,immutable"`
+	}
+
+	It("should not report violation when key is absent", func() {
+		s := S{Zone: "eu", Region: "west", Tier: Some(1)}
+		Expect(NewDecoder().ValidateOnly(&metav1.ObjectMeta{}, &s)).To(Succeed())
+		Expect(s).To(Equal(S{Zone: "eu", Region: "west", Tier: Some(1)}))
+	})
+	It("should report violation when key is present with different value", func() {
+		s := S{Zone: "eu"}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"zone": "us"}}
+		Expect(NewDecoder().ValidateOnly(m, &s)).To(MatchError(ContainSubstring("field is immutable")))
+	})
+	It("should compare with value found under fallback key", func() {
+		s := S{Region: "west"}
+		m := &metav1.ObjectMeta{Labels: map[string]string{"region": "east"}}
+		Expect(NewDecoder().ValidateOnly(m, &s)).To(MatchError(ContainSubstring("field is immutable")))
+	})
+})
+
+var _ = Describe("Byte slices and arrays with default encoding", func() {
+	type Raw []byte
+	type S struct {
+		Data  []byte   `k8s:"annotation:data"`
+		Fixed [4]byte  `k8s:"annotation:fixed"`
+		Named Raw      `k8s:"label:named"`
+		Ptr   *[]byte  `k8s:"annotation:ptr"`
+		Nums  []uint16 `k8s:"annotation:nums"`
+	}
+
+	It("should round-trip bytes as raw string", func() {
+		ptr := []byte("p,q")
+		in := S{Data: []byte("hello, world"), Fixed: [4]byte{'a', 'b', 'c', 'd'}, Named: Raw("v1"), Ptr: &ptr, Nums: []uint16{1, 2}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"data": "hello, world", "fixed": "abcd", "ptr": "p,q", "nums": "1,2"}))
+		Expect(m.Labels).To(HaveKeyWithValue("named", "v1"))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return an error when array length does not match", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"fixed": "abc"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("byte array of length 4 cannot hold 3 bytes")))
+	})
+})
+
+var _ = Describe("Decoder with RecordTouched enabled", func() {
+	type Inner struct {
+		Zone string `k8s:"annotation:zone"`
+	}
+	type S struct {
+		Name    string `k8s:"name"`
+		Mode    string `k8s:"annotation:mode|label:mode"`
+		Tier    int    `k8s:"label:tier"`
+		Missing string `k8s:"annotation:missing"`
+		Canary  bool   `k8s:"labelpresence:canary"`
+		Inner   Inner  `k8s:"inline"`
+	}
+
+	It("should record only fields with keys present in metadata", func() {
+		var touched []string
+		m := &metav1.ObjectMeta{Name: "obj", Labels: map[string]string{"mode": "on"}, Annotations: map[string]string{"zone": "eu"}}
+		Expect(Unmarshal(m, &S{}, RecordTouched(&touched))).To(Succeed())
+		Expect(touched).To(Equal([]string{"Inner.Zone", "Mode", "Name"}))
+	})
+	It("should record fields of flat types", func() {
+		type Flat struct {
+			A string `k8s:"annotation:a"`
+			B string `k8s:"annotation:b"`
+		}
+		var touched []string
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"b": "x"}}
+		Expect(Unmarshal(m, &Flat{}, RecordTouched(&touched))).To(Succeed())
+		Expect(touched).To(Equal([]string{"B"}))
+	})
+	It("should record fields indexed by several present keys once", func() {
+		type Multi struct {
+			A string     `k8s:"annotation:a,aliases:old-a"`
+			R Range[int] `k8s:"annotation:r"`
+		}
+		var touched []string
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "x", "old-a": "y", "r-min": "1", "r-max": "2"}}
+		Expect(Unmarshal(m, &Multi{}, RecordTouched(&touched))).To(Succeed())
+		Expect(touched).To(Equal([]string{"A", "R"}))
+	})
+	It("should not record fields which failed to decode", func() {
+		var touched []string
+		m := &metav1.ObjectMeta{Labels: map[string]string{"tier": "x", "canary": ""}}
+		Expect(Unmarshal(m, &S{}, RecordTouched(&touched), AccumulateFieldErrors())).NotTo(Succeed())
+		Expect(touched).To(Equal([]string{"Canary"}))
+	})
+})
+
+var _ = Describe("Decoding deletion timestamp", func() {
+	type S struct {
+		Deleting Option[time.Time] `k8s:"deletiontimestamp"`
+		Ptr      *time.Time        `k8s:"deletiontimestamp"`
+		Meta     *metav1.Time      `k8s:"deletiontimestamp"`
+	}
+	ts := metav1.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	It("should set values when object is being deleted", func() {
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{DeletionTimestamp: &ts}, &s)).To(Succeed())
+		Expect(s.Deleting).To(Equal(Some(ts.Time)))
+		Expect(*s.Ptr).To(Equal(ts.Time))
+		Expect(*s.Meta).To(Equal(ts))
+	})
+	It("should reset values when object is not being deleted", func() {
+		now := time.Now()
+		s := S{Deleting: Some(now), Ptr: &now, Meta: &ts}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(Succeed())
+		Expect(s.Deleting.IsSet()).To(BeFalse())
+		Expect(s.Ptr).To(BeNil())
+		Expect(s.Meta).To(BeNil())
+	})
+	It("should be ignored during encoding", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Deleting: Some(ts.Time)}, m)).To(Succeed())
+		Expect(m.DeletionTimestamp).To(BeNil())
+	})
+	It("should return an error for unsupported type", func() {
+		err := Unmarshal(&metav1.ObjectMeta{DeletionTimestamp: &ts}, &struct {
+			D Option[string] `k8s:"deletiontimestamp"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("timestamp requires time.Time or metav1.Time type")))
+	})
+})
+
+var _ = Describe("Fields with humanint encoding", func() {
+	type S struct {
+		Replicas int     `k8s:"annotation:replicas,enc:humanint"`
+		Limit    *uint64 `k8s:"annotation:limit,enc:humanint"`
+		Plain    int     `k8s:"annotation:plain"`
+	}
+
+	It("should decode numbers with underscores", func() {
+		s := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"replicas": "1_000", "limit": "1_000_000", "plain": "5"}}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Replicas).To(Equal(1000))
+		Expect(*s.Limit).To(Equal(uint64(1000000)))
+	})
+	It("should encode numbers without underscores", func() {
+		limit := uint64(25000)
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Replicas: 1000, Limit: &limit}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("replicas", "1000"))
+		Expect(m.Annotations).To(HaveKeyWithValue("limit", "25000"))
+	})
+	It("should not accept underscores without humanint encoding", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"plain": "1_000"}}
+		Expect(Unmarshal(m, &S{})).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("Fields with bool tokens encoding", func() {
+	type S struct {
+		Enabled bool         `k8s:"annotation:enabled,enc:bool:yes/no"`
+		Power   *bool        `k8s:"label:power,enc:bool:on/off"`
+		Debug   Option[bool] `k8s:"annotation:debug,enc:bool:yes/no"`
+		Plain   bool         `k8s:"annotation:plain"`
+		Unset   Option[bool] `k8s:"annotation:unset,enc:bool:yes/no"`
+	}
+
+	It("should round-trip configured tokens", func() {
+		power := false
+		in := S{Enabled: true, Power: &power, Debug: Some(false), Plain: true}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"enabled": "yes", "debug": "no", "plain": "true"}))
+		Expect(m.Labels).To(Equal(map[string]string{"power": "off"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should accept standard representations", func() {
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"enabled": "true"}}, &s)).To(Succeed())
+		Expect(s.Enabled).To(BeTrue())
+	})
+	It("should return an error for unknown token", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"enabled": "maybe"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("invalid bool value 'maybe', expected 'yes' or 'no'")))
+	})
+	It("should return an error for non-bool field", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"a": "yes"}}, &struct {
+			A string `k8s:"annotation:a,enc:bool:yes/no"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("bool encoding requires bool type")))
+	})
+	It("should return an error for invalid tokens", func() {
+		err := Unmarshal(&metav1.ObjectMeta{}, &struct {
+			A bool `k8s:"annotation:a,enc:bool:yes/yes"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("invalid bool tokens 'yes/yes'")))
+	})
+})
+
+var _ = Describe("Decoding fields with aliases precedence", func() {
+	m := &metav1.ObjectMeta{Annotations: map[string]string{"old-mode": "old", "new-mode": "new"}}
+
+	It("should prefer the key by default", func() {
+		s := struct {
+			Mode string `k8s:"annotation:old-mode,aliases:new-mode"`
+		}{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Mode).To(Equal("old"))
+	})
+	It("should prefer aliases with aliasfirst", func() {
+		s := struct {
+			Mode string `k8s:"annotation:old-mode,aliases:new-mode,aliasfirst"`
+		}{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Mode).To(Equal("new"))
+	})
+	It("should fall back to the key when alias is absent", func() {
+		s := struct {
+			Mode string `k8s:"annotation:old-mode,aliases:newer-mode,aliasfirst"`
+		}{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Mode).To(Equal("old"))
+	})
+	It("should reject aliasfirst without aliases", func() {
+		err := Unmarshal(m, &struct {
+			Mode string `k8s:"annotation:old-mode,aliasfirst"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("'aliasfirst' can be used only with 'aliases'")))
+	})
+})
+
+var _ = Describe("Looking up accumulated errors by field path", func() {
+	type Inner struct {
+		Replicas int `k8s:"annotation:replicas"`
+	}
+	type S struct {
+		Mode  bool   `k8s:"label:mode"`
+		Zone  string `k8s:"annotation:zone,immutable"`
+		Inner Inner  `k8s:"inline"`
+	}
+	m := &metav1.ObjectMeta{Annotations: map[string]string{"replicas": "x", "zone": "eu"}, Labels: map[string]string{"mode": "y"}}
+
+	It("should return decode error of given field", func() {
+		err := Unmarshal(m, &S{}, AccumulateFieldErrors())
+		var lookup FieldErrorLookup
+		Expect(errors.As(err, &lookup)).To(BeTrue())
+		Expect(lookup.ForField("Inner.Replicas")).NotTo(BeNil())
+		Expect(lookup.ForField("Inner.Replicas").Field).To(Equal("metadata.annotation"))
+		Expect(lookup.ForField("Inner.Replicas").BadValue).To(Equal("replicas"))
+		Expect(lookup.ForField("Mode").BadValue).To(Equal("mode"))
+		Expect(lookup.ForField("Zone")).To(BeNil())
+	})
+	It("should return validation error of given field", func() {
+		err := Unmarshal(m, &S{Zone: "us"}, Validate(true), AccumulateFieldErrors())
+		var lookup FieldErrorLookup
+		Expect(errors.As(err, &lookup)).To(BeTrue())
+		Expect(lookup.ForField("Zone").Detail).To(ContainSubstring("field is immutable"))
+	})
+	It("should return decode error of field of flat type", func() {
+		err := Unmarshal(m, &struct {
+			Replicas int `k8s:"annotation:replicas"`
+		}{}, AccumulateFieldErrors())
+		var lookup FieldErrorLookup
+		Expect(errors.As(err, &lookup)).To(BeTrue())
+		Expect(lookup.ForField("Replicas")).NotTo(BeNil())
+	})
+	It("should return encode error of given field", func() {
+		err := Marshal(&struct {
+			A uintptr `k8s:"annotation:a"`
+			B int     `k8s:"annotation:b"`
+		}{}, &metav1.ObjectMeta{}, AccumulateEncodeFieldErrors())
+		var lookup FieldErrorLookup
+		Expect(errors.As(err, &lookup)).To(BeTrue())
+		Expect(lookup.ForField("A")).NotTo(BeNil())
+		Expect(lookup.ForField("B")).To(BeNil())
+	})
+})
+
+var _ = Describe("Decoding and encoding with context", func() {
+	type Step int
+	type S struct {
+		A Step `k8s:"annotation:a"`
+		B Step `k8s:"label:b"`
+		C *int `k8s:"annotation:c"`
+	}
+	m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "1", "c": "3"}, Labels: map[string]string{"b": "2"}}
+
+	It("should not decode anything with cancelled context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s := S{}
+		Expect(NewDecoder().DecodeContext(ctx, m, &s)).To(MatchError(context.Canceled))
+		Expect(s).To(Equal(S{}))
+	})
+	It("should stop decoding when context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		decoded := 0
+		dec := NewDecoder().RegisterCodec(reflect.TypeOf(Step(0)), func(out reflect.Value, in string) error {
+			decoded++
+			cancel()
+			return nil
+		})
+		err := dec.DecodeContext(ctx, m, &S{})
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(decoded).To(Equal(1))
+	})
+	It("should decode all fields with active context", func() {
+		s := S{}
+		Expect(NewDecoder().DecodeContext(context.Background(), m, &s)).To(Succeed())
+		Expect(s.A).To(Equal(Step(1)))
+		Expect(*s.C).To(Equal(3))
+	})
+	It("should stop encoding when context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		encoded := 0
+		enc := NewEncoder().RegisterCodec(reflect.TypeOf(Step(0)), func(reflect.Value) (string, error) {
+			encoded++
+			cancel()
+			return "x", nil
+		})
+		err := enc.EncodeContext(ctx, &S{A: 1, B: 2}, &metav1.ObjectMeta{})
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(encoded).To(Equal(1))
+	})
+})
+
+var _ = Describe("Finalizers", func() {
+	type S struct {
+		Finalizers []string `k8s:"finalizers"`
+	}
+	type Finalizer string
+	type Named struct {
+		Finalizers []Finalizer `k8s:"finalizers,omitempty"`
+	}
+
+	It("should round-trip multiple finalizers", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Finalizers: []string{"example.com/a", "example.com/b"}}, m)).To(Succeed())
+		Expect(m.Finalizers).To(Equal([]string{"example.com/a", "example.com/b"}))
+		s := S{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Finalizers).To(Equal([]string{"example.com/a", "example.com/b"}))
+	})
+	It("should round-trip empty finalizers", func() {
+		m := &metav1.ObjectMeta{Finalizers: []string{"example.com/a"}}
+		Expect(Marshal(&S{Finalizers: []string{}}, m)).To(Succeed())
+		Expect(m.Finalizers).To(BeEmpty())
+		s := S{Finalizers: []string{"stale"}}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Finalizers).To(BeEmpty())
+	})
+	It("should not share backing array with metadata", func() {
+		m := &metav1.ObjectMeta{Finalizers: []string{"example.com/a"}}
+		s := S{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		s.Finalizers[0] = "changed"
+		Expect(m.Finalizers).To(Equal([]string{"example.com/a"}))
+	})
+	It("should support slices of named string types", func() {
+		m := &metav1.ObjectMeta{Finalizers: []string{"example.com/a"}}
+		s := Named{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Finalizers).To(Equal([]Finalizer{"example.com/a"}))
+	})
+	It("should write finalizers with ValidateBeforeWrite", func() {
+		m := &metav1.ObjectMeta{Finalizers: []string{"example.com/old"}}
+		Expect(Marshal(&S{Finalizers: []string{"example.com/a", "example.com/b"}}, m, ValidateBeforeWrite())).To(Succeed())
+		Expect(m.Finalizers).To(Equal([]string{"example.com/a", "example.com/b"}))
+	})
+	It("should keep finalizers not managed by struct with ValidateBeforeWrite", func() {
+		m := &metav1.ObjectMeta{Finalizers: []string{"example.com/a"}}
+		Expect(Marshal(&Named{}, m, ValidateBeforeWrite())).To(Succeed())
+		Expect(m.Finalizers).To(Equal([]string{"example.com/a"}))
+	})
+	It("should leave existing finalizers intact for empty field with omitempty", func() {
+		m := &metav1.ObjectMeta{Finalizers: []string{"example.com/a"}}
+		Expect(Marshal(&Named{}, m)).To(Succeed())
+		Expect(m.Finalizers).To(Equal([]string{"example.com/a"}))
+	})
+	It("should return an error for unsupported type", func() {
+		err := Unmarshal(&metav1.ObjectMeta{}, &struct {
+			F string `k8s:"finalizers"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("'finalizers' requires slice of string kind")))
+	})
+})
+
+var _ = Describe("Fields with labelset encoding", func() {
+	type S struct {
+		Selector labels.Set        `k8s:"annotation:selector,enc:labelset"`
+		Plain    map[string]string `k8s:"annotation:plain,enc:labelset,omitempty"`
+	}
+
+	It("should decode label selector", func() {
+		s := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"selector": "app=foo,tier=bar", "plain": "a=1"}}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Selector).To(Equal(labels.Set{"app": "foo", "tier": "bar"}))
+		Expect(s.Plain).To(Equal(map[string]string{"a": "1"}))
+	})
+	It("should round-trip label set", func() {
+		m := &metav1.ObjectMeta{}
+		in := S{Selector: labels.Set{"tier": "bar", "app": "foo"}, Plain: map[string]string{"a": "1"}}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"selector": "app=foo,tier=bar", "plain": "a=1"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return an error for invalid selector", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"selector": "app!=foo"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("invalid label selector 'app!=foo'")))
+	})
+	It("should return an error for unsupported type", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"s": "a=b"}}, &struct {
+			S []string `k8s:"annotation:s,enc:labelset"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("labelset encoding requires map with string keys and values")))
+	})
+})
+
+var _ = Describe("Decoding generation", func() {
+	type S struct {
+		Generation int64         `k8s:"generation"`
+		Ptr        *int64        `k8s:"generation"`
+		Opt        Option[int32] `k8s:"generation"`
+	}
+
+	It("should set values from object's generation", func() {
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Generation: 7}, &s)).To(Succeed())
+		Expect(s.Generation).To(Equal(int64(7)))
+		Expect(*s.Ptr).To(Equal(int64(7)))
+		Expect(s.Opt).To(Equal(Some(int32(7))))
+	})
+	It("should be ignored during encoding", func() {
+		m := &metav1.ObjectMeta{Generation: 3}
+		Expect(Marshal(&S{Generation: 9}, m)).To(Succeed())
+		Expect(m.Generation).To(Equal(int64(3)))
+		Expect(m.Annotations).To(BeEmpty())
+		Expect(m.Labels).To(BeEmpty())
+	})
+	It("should return an error for unsupported type", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Generation: 1}, &struct {
+			G string `k8s:"generation"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("generation requires integer kind, got 'string'")))
+	})
+})
+
+// countingMeta counts calls of annotations and labels accessors, which return copies like some metav1.Object
+// implementations do.
+type countingMeta struct {
+	*metav1.ObjectMeta
+	annotationCalls, labelCalls int
+}
+
+func (m *countingMeta) GetAnnotations() map[string]string {
+	m.annotationCalls++
+	return maps.Clone(m.ObjectMeta.GetAnnotations())
+}
+
+func (m *countingMeta) GetLabels() map[string]string {
+	m.labelCalls++
+	return maps.Clone(m.ObjectMeta.GetLabels())
+}
+
+var _ = Describe("Decoding metadata with accessors returning copies", func() {
+	type S struct {
+		A  string            `k8s:"annotation:a,immutable"`
+		B  int               `k8s:"annotation:b|label:b"`
+		C  []string          `k8s:"label:c"`
+		D  bool              `k8s:"labelpresence:d"`
+		Ls map[string]string `k8s:"labels"`
+	}
+
+	It("should read annotations and labels once", func() {
+		m := &countingMeta{ObjectMeta: &metav1.ObjectMeta{
+			Annotations: map[string]string{"a": "x", "b": "1"},
+			Labels:      map[string]string{"c": "p,q", "d": ""},
+		}}
+		s := S{A: "x"}
+		Expect(Unmarshal(m, &s, Validate(true))).To(Succeed())
+		Expect(s).To(Equal(S{A: "x", B: 1, C: []string{"p", "q"}, D: true, Ls: map[string]string{"c": "p,q", "d": ""}}))
+		Expect(m.annotationCalls).To(Equal(1))
+		Expect(m.labelCalls).To(Equal(1))
+	})
+})
@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Fields with default value", func() {
+	type S struct {
+		Replicas int    `k8s:"annotation:replicas,default:3,omitempty"`
+		Tier     string `k8s:"label:tier,aliases:layer,default:web"`
+		Other    int    `k8s:"annotation:other"`
+	}
+
+	It("should decode default when key is absent", func() {
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s)).To(Succeed())
+		Expect(s).To(Equal(S{Replicas: 3, Tier: "web"}))
+	})
+	It("should decode present keys and aliases instead of default", func() {
+		s := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"replicas": "5"}, Labels: map[string]string{"layer": "db"}}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s).To(Equal(S{Replicas: 5, Tier: "db"}))
+	})
+	It("should remove key when value equals default with omitempty", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"replicas": "5"}}
+		Expect(Marshal(&S{Replicas: 3, Tier: "web", Other: 1}, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"other": "1"}))
+		Expect(m.Labels).To(Equal(map[string]string{"tier": "web"}))
+	})
+	It("should round-trip defaulted value without persisting it", func() {
+		m := &metav1.ObjectMeta{}
+		s := S{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(Marshal(&s, m)).To(Succeed())
+		Expect(m.Annotations).ToNot(HaveKey("replicas"))
+	})
+	It("should encode value different from default", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Replicas: 4}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("replicas", "4"))
+	})
+	It("should round-trip zero value different from default", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"replicas": "5"}}
+		Expect(Marshal(&S{}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("replicas", "0"))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.Replicas).To(Equal(0))
+	})
+	It("should return an error for default of unsupported source", func() {
+		err := Unmarshal(&metav1.ObjectMeta{}, &struct {
+			Name string `k8s:"name,default:x"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("'default' can be used only with 'annotation' or 'label'")))
+	})
+})
@@ -0,0 +1,61 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import "fmt"
+
+// DefaultOption sets behavior of Decoder or Encoder applied to all fields unless it is overridden in struct tags.
+type DefaultOption func(d *defaults) error
+
+type defaults struct {
+	enc    encoder
+	params encParams
+}
+
+// DefaultEncoding sets encoding scheme of annotation, label and provided fields without 'enc' tag. name follows syntax
+// of 'enc' tag value (e.g. "json", "base:16" or "bool:yes/no"). 'custom' encoding cannot be used as default.
+func DefaultEncoding(name string) DefaultOption {
+	return func(d *defaults) error {
+		enc, params, err := parseEncoding(name)
+		if err != nil {
+			return fmt.Errorf("invalid default encoding: %w", err)
+		}
+		if enc == custom {
+			return fmt.Errorf("invalid default encoding: '%s' can be used only in struct tags", customKey)
+		}
+		d.enc, d.params = enc, params
+		return nil
+	}
+}
+
+func newDefaults(opts []DefaultOption) (defaults, error) {
+	d := defaults{enc: encoder(undefined)}
+	for _, opt := range opts {
+		if err := opt(&d); err != nil {
+			return defaults{}, err
+		}
+	}
+	return d, nil
+}
+
+// encoding returns encoding scheme and its parameters of field with tag.
+func (d *defaults) encoding(tag *parsedTag) (encoder, encParams) {
+	if tag.enc == encoder(undefined) && (tag.source == annotation || tag.source == label || tag.source == provided) {
+		return d.enc, d.params
+	}
+	return tag.enc, tag.params
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Decoder and Encoder with defaults", func() {
+	type Config struct {
+		Replicas int      `json:"replicas"`
+		Zones    []string `json:"zones"`
+	}
+	type S struct {
+		Config Config   `k8s:"annotation:config"`
+		Tags   []string `k8s:"label:tags"`
+		Raw    []byte   `k8s:"annotation:raw,enc:hex"`
+		Name   string   `k8s:"name"`
+	}
+
+	It("should use default encoding for fields without enc tag", func() {
+		enc, err := NewEncoderWithDefaults(DefaultEncoding("json"))
+		Expect(err).NotTo(HaveOccurred())
+		dec, err := NewDecoderWithDefaults(DefaultEncoding("json"))
+		Expect(err).NotTo(HaveOccurred())
+		in := S{Config: Config{Replicas: 2, Zones: []string{"a", "b"}}, Tags: []string{"x"}, Raw: []byte("hi"), Name: "obj"}
+		m := &metav1.ObjectMeta{}
+		Expect(enc.Encode(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"config": `{"replicas":2,"zones":["a","b"]}`, "raw": "6869"}))
+		Expect(m.Labels).To(Equal(map[string]string{"tags": `["x"]`}))
+		Expect(m.Name).To(Equal("obj"))
+		out := S{}
+		Expect(dec.Decode(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should use default encoding to check immutable fields", func() {
+		enc, err := NewEncoderWithDefaults(DefaultEncoding("json"))
+		Expect(err).NotTo(HaveOccurred())
+		type I struct {
+			Zones []string `k8s:"annotation:zones,immutable"`
+		}
+		m := &metav1.ObjectMeta{}
+		Expect(enc.Encode(&I{Zones: []string{"a", "b"}}, m)).To(Succeed())
+		Expect(enc.Encode(&I{Zones: []string{"a", "b"}}, m, EnforceImmutable())).To(Succeed())
+		Expect(enc.Encode(&I{Zones: []string{"a"}}, m, EnforceImmutable())).To(MatchError(ContainSubstring("field is immutable")))
+	})
+	It("should keep default scheme without defaults", func() {
+		dec, err := NewDecoderWithDefaults()
+		Expect(err).NotTo(HaveOccurred())
+		out := struct {
+			Tags []string `k8s:"label:tags"`
+		}{}
+		Expect(dec.Decode(&metav1.ObjectMeta{Labels: map[string]string{"tags": "a,b"}}, &out)).To(Succeed())
+		Expect(out.Tags).To(Equal([]string{"a", "b"}))
+	})
+	It("should support parametrized encodings", func() {
+		enc, err := NewEncoderWithDefaults(DefaultEncoding("base:16"))
+		Expect(err).NotTo(HaveOccurred())
+		m := &metav1.ObjectMeta{}
+		Expect(enc.Encode(&struct {
+			V int `k8s:"annotation:v"`
+		}{V: 255}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("v", "ff"))
+	})
+	It("should return error for invalid encodings", func() {
+		_, err := NewDecoderWithDefaults(DefaultEncoding("yaml"))
+		Expect(err).To(MatchError(ContainSubstring("invalid default encoding")))
+		_, err = NewEncoderWithDefaults(DefaultEncoding("custom"))
+		Expect(err).To(MatchError(ContainSubstring("'custom' can be used only in struct tags")))
+	})
+})
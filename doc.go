@@ -32,21 +32,49 @@ limitations under the License.
 // Supported tag values:
 //   - annotation - indicate if field should be serialized/deserialized from k8s Annotations map. The annotation should follow "annotation:<key>" syntax, where <key> should be valid k8s [annotation]
 //   - label - indicate if field should be serialized/deserialized from k8s Labels map. The annotation should follow "label:<key>" syntax, where <key> should be valid k8s [label]
+//   - alternative sources - annotation and label references can be joined with '|' (e.g. "annotation:<key>|label:<key>"). During decoding the first present key is used. During encoding only the first key is written.
+//   - labelpresence - indicate if bool field should be serialized/deserialized from existence of k8s label. The tag should follow "labelpresence:<key>" syntax. Existing label with empty value is deserialized as true. Optional "absent:<bool>" tag sets the value of field when the label does not exist (false by default). During serialization the label is removed when field value equals absent value.
+//   - labels, annotations - indicate if map[string]string field should be serialized/deserialized from all labels or annotations. During serialization the map is merged into metadata (see ReplaceManagedMaps option) and keys written by other fields take precedence.
+//   - creationtimestamp - indicate if field of time.Time, metav1.Time or pointer or metaser.Option of them should be deserialized from object's creation timestamp. The field is ignored during serialization.
+//   - deletiontimestamp - indicate if field of time.Time, metav1.Time or pointer or metaser.Option of them should be deserialized from object's deletion timestamp. Nil pointer or unset Option means the object is not being deleted. The field is ignored during serialization.
+//   - generation - indicate if field of integer kind or pointer or metaser.Option of it should be deserialized from object's generation. The field is ignored during serialization.
+//   - finalizers - indicate if []string field should be serialized/deserialized from k8s Finalizers. During serialization the finalizers are replaced with the field value; empty slice removes all finalizers unless the field is tagged with omitempty.
+//   - src - indicate if field should be serialized/deserialized using custom SourceProvider registered with DecodeSourceProvider/EncodeSourceProvider options. The tag should follow "src:<provider>=<key>" syntax.
 //   - name - indicate if field should be serialized/deserialized from k8s Name value.
+//   - template - composes name from values of other tagged fields during serialization. The tag should follow "template:<format>" syntax, where fields are referenced by {<field path>} placeholders (e.g. "name,out,template:{Prefix}-{Inner.Suffix}"). Referenced values are serialized with their encodings. Can be used only with 'name' and 'out' tags, as composed name cannot be deserialized.
 //   - namespace - indicate if field should be serialized/deserialized from k8s Namespace field value.
+//   - owner - indicate if field should be serialized/deserialized from k8s OwnerReferences. The tag may follow "owner:<kind>" syntax to use only references of given kind. Slice fields receive all matching references, other fields receive the first one. Fields of other type than metav1.OwnerReference are converted through json representation.
 //   - enc - sets encoding/decoding scheme for field. If ommited default schema will be used (see Supported types section for more info). If type is not in supported type list the TextMarshaler/TextUnmarshaler will be used. Tag should follow enc:<val> syntax, where val is one of supported values defined in Encoding schemes section.
 //   - in - indicate if field should be used during decoding and ignored during encoding
 //   - inout - indicate if field should be used during decoding and encoding. This is default value if 'in' or 'out' is not set explicitly.
 //   - out - indicate if field should be used during encoding and ignored during decoding
 //   - inline - can be only used on struct fields. Inline all contained structure fields into outer struct.
-//   - omitempty - do not encode field if have zero value. If the annotation or label exists it will be removed from metadata.
-//   - immutable - the value of field cannot change during decoding.
-//   - aliases - specify alternative keys for annotations or labels loopkup. Can be used only with 'annotation' or 'label' tag. The tag have following syntax: 'aliases:value1;value2;value3'. Values should be a valid k8s annotation or label key.
-//   - setonce - the value can change from zero value to non-zero value. It implies that field is immutable after set to non-zero value.
+//   - prefix - can be only used with 'inline' tag. Prepends the value to annotation and label keys (including aliases) of all fields contained in inlined struct. The tag should follow "prefix:<value>" syntax. Prefixes of nested inline structs are concatenated.
+//   - default - sets raw value of annotation or label which is deserialized when the key (and its aliases and alternative sources) is absent. The tag should follow "default:<value>" syntax. Combined with omitempty the key is removed during serialization only when encoded value equals the default, so defaulted values are not persisted, while zero value is encoded.
+//   - omitempty - do not encode field if have zero value. If the annotation or label exists it will be removed from metadata. Existing name and namespace are left intact. metaser.Option is empty only if it is not set, so e.g. Some(0) is encoded. Slices and maps without elements are empty even if they are not nil. Annotations and labels with default value (see default tag) are removed only when encoded value equals the default.
+//   - immutable - the value of field cannot change during decoding. Absent annotations and labels are not validated. Unset metaser.Option differs from set one, while set options are compared by contained value.
+//   - aliases - specify alternative keys for annotations or labels loopkup. Can be used only with 'annotation' or 'label' tag. The tag have following syntax: 'aliases:value1;value2;value3'. Values should be a valid k8s annotation or label key. The key takes precedence over aliases during decoding.
+//   - aliasfirst - aliases take precedence over the key during decoding (e.g. when alias is a new key replacing the old one). Can be used only with 'aliases' tag.
+//   - percentint - the integer value must be within [0, 100] range during decoding and encoding. Decoded value may have '%' suffix. Use 'percentint:suffix' to append '%' suffix during encoding. Cannot be combined with 'enc' tag.
+//   - maxbytes - limits length of encoded annotation or label value. The tag should follow "maxbytes:<n>" syntax. Encoding returns an error when value exceeds the limit.
+//   - dns1123label, dns1123subdomain - the raw value of name, namespace, annotation or label must be a valid DNS-1123 label or subdomain. Checked during decoding and encoding. Empty values are not validated.
+//   - fold - the annotation or label value is lowercased before decoding when CaseInsensitive option is used. Can be used only with string-kinded fields (or pointers and metaser.Option of them) without 'enc' tag.
+//   - setonce - the value can change from zero value to non-zero value. It implies that field is immutable after set to non-zero value. During encoding with PreserveSetOnce option the field is written only if it is not already set in metadata.
 //
-// Encoding schemes:
-//   - json - field will deserialized/serialized with json decoder/encoder
-//   - custom - field will be deserialized/serialized with metaser.MetadataUnmarshaler/metaser.MetadataMarshaler interface.
+// Encoding schemes (encoding of annotation, label and provided fields without 'enc' tag can be set with
+// NewDecoderWithDefaults/NewEncoderWithDefaults and DefaultEncoding):
+//   - json - field will deserialized/serialized with json decoder/encoder. json.RawMessage is stored verbatim (it must be valid JSON during serialization). Use "enc:json:indent" to serialize human-readable JSON indented with two spaces.
+//   - binary - field will be deserialized/serialized with encoding.BinaryUnmarshaler/encoding.BinaryMarshaler interface. Bytes are stored as standard base64 string.
+//   - hex - field of byte slice or array type is deserialized/serialized as hex string.
+//   - ttl - field of time.Time or time.Duration type is serialized as RFC3339 expiry timestamp. Duration is added to current time during serialization and during deserialization it is set to time remaining until expiry (negative when expired). Current time can be set with EncodeClock/DecodeClock options.
+//   - bool:<true>/<false> - field of bool type (or pointer or metaser.Option of it) is serialized as one of given tokens (e.g. "enc:bool:yes/no"). Deserialization accepts the tokens and values accepted by strconv.ParseBool.
+//   - stringer - field implementing fmt.Stringer (e.g. enum defined as typed constants) is serialized with String method. Deserialization matches the value against values registered with Decoder.RegisterStringer.
+//   - labelset - field of map type with string keys and values (e.g. labels.Set) is deserialized/serialized as equality-based label selector (e.g. "app=foo,tier=bar") using k8s.io/apimachinery/pkg/labels. Keys are serialized in sorted order.
+//   - orderedmap - field of slice of structs with 'Key' and 'Value' fields (e.g. []struct{ Key, Value string }) is deserialized/serialized as comma separated list of <key>:<value> pairs like map, but order of pairs is preserved. Serialized keys cannot contain comma or colon and values cannot contain comma.
+//   - humanint - field of big.Int or integer type is deserialized from decimal number which may contain underscores separating digits (e.g. "1_000"). Numbers are serialized without underscores.
+//   - base:<n> - field of big.Int or integer type is deserialized/serialized as number in base n, where n is within [2, 36] range (e.g. "enc:base:16").
+//   - default scheme of slices, arrays and maps joins elements with "," and map keys and values with ":". Empty collections are serialized as empty string, so nil and empty collections are not distinguished. Elements containing separators corrupt the value and single empty element is decoded as empty collection. Unset metaser.Option cannot be an element of such collection, because it is indistinguishable from empty element, so encoding returns an error. With AutoJSONCollections encoder option such collections are serialized as JSON prefixed with "@json:", which is recognized by decoder with DecodeAutoJSONCollections option.
+//   - custom - field will be deserialized/serialized with metaser.MetadataUnmarshaler/metaser.MetadataMarshaler interface. Nil pointers are skipped during serialization unless ErrorOnNilCustom encoder option is used. Field may be metaser.Option of such type, which is set after successful deserialization and skipped during serialization when unset. If field is a slice, every element is deserialized/serialized separately with metadata view containing only its own keys. Keys of element at index i are stored as "<field>.item-<i>-<key>", where <field> is dot separated path of Go field names (e.g. "Items.item-0-name"), so keys of different fields and other annotations are not affected.
 //
 // Supported types:
 //   - bool - serialized/deserialized using strconv package.
@@ -55,11 +83,27 @@ limitations under the License.
 //   - float32, float64  - serialized/deserialized using strconv package.
 //   - string
 //   - array - encodes field as comma separated list of elements. Serialized elements cannot contain comma.
-//   - slice - encodes field as comma separated list of elements. Serialized elements cannot contain comma.
-//   - map - encodes field as comma separated list of <key>:<value> pairs. Serialized elements cannot contain comma or semicolon.
+//   - slice - encodes field as comma separated list of elements. Serialized elements cannot contain comma. Slices and arrays of structs require 'enc:json' tag, unless the struct implements encoding.TextMarshaler/encoding.TextUnmarshaler.
+//   - []byte, [N]byte - serialized/deserialized as raw string, not as list of numbers. Array length must match length of deserialized string.
+//   - map - encodes field as comma separated list of <key>:<value> pairs sorted by encoded key. Keys and values implementing encoding.TextMarshaler/encoding.TextUnmarshaler are serialized with these interfaces. Serialized elements cannot contain comma or semicolon.
 //   - pointers - pointers will be dereferenced during serialization/deserialization.
+//   - metav1.Duration - serialized/deserialized as Go duration string (e.g. "30s"), the same way as by Kubernetes API.
 //   - struct - structs can be only used with 'inline' tag.
-//   - metaser.Option[T] - generic struct representing optional value.
+//   - metaser.Option[T] - generic struct representing optional value. Unset Option removes the annotation or label during serialization (see KeepUnsetOptions).
+//   - metaser.Envelope[T] - versioned data serialized as {"version":<n>,"data":<json>}. Pointer to T may implement EnvelopeMigrator to migrate data stored in older versions during deserialization.
+//   - metaser.Range[T] - range of ordered values serialized into two annotations or labels: "<key>-min" and "<key>-max". Min must not be greater than Max. May be held by pointer or metaser.Option, then nil pointer or unset Option means both keys are absent. Cannot be used with aliases, alternative sources or "enc" tag.
+//
+// Tagged fields of chan, func and unsafe.Pointer types (including pointers to them, options and collections of them)
+// are reported by Decode and Encode as UnsupportedTypeError before any field is processed.
+//
+// Types implementing only one of encoding.TextMarshaler and encoding.TextUnmarshaler must be used with 'in' or 'out' tag
+// accordingly. Otherwise Decode and Encode return an error.
+//
+// Values produced by encoding.TextMarshaler are written verbatim. RequireUTF8 encoder option makes Encode return an error
+// for values which are not valid UTF-8, as such values are rejected by API server.
+//
+// Serialization of any type can be customized with Encoder.RegisterCodec and Decoder.RegisterCodec. Registered codecs
+// take precedence over encoding.TextMarshaler/encoding.TextUnmarshaler and default encoding scheme.
 //
 // Limitations:
 //   - current implemntation does not support reference cycles inside decoded and encoded structs. The result of such operations is undefined.
@@ -17,32 +17,215 @@ limitations under the License.
 package metaser
 
 import (
+	"context"
 	"encoding"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // Encoder encodes and writes data into Kubernets Object's metatdata
-type Encoder struct{}
+type Encoder struct {
+	// cache maps reflect.Type of every processed type to its *typeCache.
+	cache    sync.Map
+	now      func() time.Time
+	codecs   map[reflect.Type]func(reflect.Value) (string, error)
+	defaults defaults
+}
 
 // internal struct represents context of encoding operation.
 type encodeContext struct {
-	meta metav1.Object
-	out  struct {
+	cache *typeCache
+	root  reflect.Value
+	meta  metav1.Object
+	out   struct {
 		Labels      map[string]string
 		Annotations map[string]string
 	}
-	values []structField
+	written struct {
+		Labels      map[string]struct{}
+		Annotations map[string]struct{}
+	}
+	values              []structField
+	pruneManagedKeys    bool
+	overwrite           bool
+	validateKeys        bool
+	validateBeforeWrite bool
+	validateValues      bool
+	enforceImmutable    bool
+	accumulateErrors    bool
+	keepUnsetOptions    bool
+	cleanupAliases      bool
+	preserveSetOnce     bool
+	providers           map[string]SourceProvider
+	autoJSON            bool
+	errorOnNilCustom    bool
+	requireUTF8         bool
+	replaceMaps         bool
+	wholeMaps           []structField
+	keyFunc             func(src source, key string) string
+	codecs              map[reflect.Type]func(reflect.Value) (string, error)
+	defaults            defaults
+	fieldErrors         field.ErrorList
+	errorFields         map[string]*field.Error
+	ctx                 context.Context
+	now                 func() time.Time
 }
 
 // EncodeOption to be passed to Encode()
 type EncodeOption func(enc *encodeContext)
 
+// PruneManagedKeys enforces encoder to remove from metadata all annotations and labels known to the encoded
+// struct (including aliases) which were not written during encoding. Keys of input-only fields are left intact.
+func PruneManagedKeys() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.pruneManagedKeys = true
+	}
+}
+
+// Overwrite controls whether annotations and labels not managed by the encoded struct (e.g. written by encoding of
+// other struct into the same metadata) are preserved (default) or removed. With enabled overwrite metadata contains
+// only keys of the encoded struct, including content of fields tagged with 'labels' or 'annotations' and keys written
+// by 'enc:custom' fields. Custom fields are then encoded into metadata view containing only their own keys. Keys
+// shared by both structs are always overwritten by the last encoded one.
+func Overwrite(enabled bool) EncodeOption {
+	return func(enc *encodeContext) {
+		enc.overwrite = enabled
+	}
+}
+
+// ValidateEncodedKeys enforces encoder to check if annotation and label keys (including aliases) used in struct tags
+// are valid Kubernetes keys before any change is made to metadata.
+func ValidateEncodedKeys() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.validateKeys = true
+	}
+}
+
+// ValidateBeforeWrite enforces encoder to encode all fields into a scratch copy of metadata first and validate
+// resulting name, namespace, labels and annotations (syntax, length and total size). The result is written into
+// metadata only if validation passes, otherwise metadata is left untouched.
+func ValidateBeforeWrite() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.validateBeforeWrite = true
+	}
+}
+
+// ValidateValues enforces encoder to validate every encoded label value (length and syntax) and annotation value
+// (size limit of annotations) before writing it into metadata. Unlike ValidateBeforeWrite the violation is reported
+// for the field which produced the value.
+func ValidateValues() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.validateValues = true
+	}
+}
+
+// EnforceImmutable enforces encoder to return an error when value of field marked as immutable
+// differs from the value already present in metadata.
+func EnforceImmutable() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.enforceImmutable = true
+	}
+}
+
+// AccumulateEncodeFieldErrors enforces encoder to accumulate
+// all encountered encode errors instead of aborting on first found one.
+// the list of errors can be obtained with GetErrorList() function.
+func AccumulateEncodeFieldErrors() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.accumulateErrors = true
+	}
+}
+
+// KeepUnsetOptions enforces encoder to write unset Option fields as empty strings instead of removing
+// corresponding annotations and labels from metadata.
+func KeepUnsetOptions() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.keepUnsetOptions = true
+	}
+}
+
+// CleanupAliases enforces encoder to remove alias keys of annotations and labels from metadata
+// when canonical key is written. It allows to migrate keys, so readers of old keys stop seeing them.
+func CleanupAliases() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.cleanupAliases = true
+	}
+}
+
+// PreserveSetOnce enforces encoder to skip fields marked as 'setonce' when their value is already set
+// in metadata to non-empty value. The field is written only if it is currently unset.
+func PreserveSetOnce() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.preserveSetOnce = true
+	}
+}
+
+// AutoJSONCollections enforces encoder to serialize slices, arrays and maps using default encoding scheme as JSON
+// prefixed with "@json:" when their elements contain item (',') or key-value (':') separators, which would corrupt
+// the value, or when they contain single empty element, which would be decoded as empty collection. Such values are
+// decoded with DecodeAutoJSONCollections decoder option.
+func AutoJSONCollections() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.autoJSON = true
+	}
+}
+
+// ErrorOnNilCustom enforces encoder to return an error when field tagged with 'enc:custom' is a nil pointer.
+// By default such fields are skipped.
+func ErrorOnNilCustom() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.errorOnNilCustom = true
+	}
+}
+
+// RequireUTF8 enforces encoder to return an error when encoding.TextMarshaler produces value which is not valid UTF-8.
+// Such values are rejected by API server, so the error is reported for the field which produced the value.
+func RequireUTF8() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.requireUTF8 = true
+	}
+}
+
+// ReplaceManagedMaps enforces encoder to replace all labels or annotations with content of fields tagged
+// with 'labels' or 'annotations'. Keys written by other fields are kept. By default the maps are merged.
+func ReplaceManagedMaps() EncodeOption {
+	return func(enc *encodeContext) {
+		enc.replaceMaps = true
+	}
+}
+
+// EncodeKeyFunc enforces encoder to transform annotation and label keys used in struct tags (including aliases
+// and alternative sources) with fn before writing them into metadata. fn is called with "annotation" or "label"
+// as source and the key. It allows to e.g. add organization-specific prefix to keys for single Encode call.
+func EncodeKeyFunc(fn func(source, key string) string) EncodeOption {
+	return func(enc *encodeContext) {
+		enc.keyFunc = func(src source, key string) string { return fn(src.String(), key) }
+	}
+}
+
+// EncodeClock sets source of current time used by time-dependent encoders (e.g. 'ttl').
+func EncodeClock(now func() time.Time) EncodeOption {
+	return func(enc *encodeContext) {
+		enc.now = now
+	}
+}
+
 func encodeUsingTextMarshaler(in reflect.Value) (string, error) {
 	fun := method(in, "MarshalText")
 	if !fun.IsValid() || fun.IsZero() {
@@ -61,36 +244,155 @@ func encodeUsingTextMarshaler(in reflect.Value) (string, error) {
 	return string(ret[0].Bytes()), nil
 }
 
-func encodeUndefined(in reflect.Value) (string, error) {
+func encodeUndefined(ec *encodeContext, in reflect.Value) (string, error) {
 	if !in.IsValid() {
 		return "", fmt.Errorf("unable to encode invalid value")
 	}
-	// first try to check if TextMarshaler is defined for type
+	if codec, ok := ec.codecs[in.Type()]; ok {
+		return codec(in)
+	}
+	if in.Kind() == reflect.Pointer && ec.codecs[in.Type().Elem()] != nil {
+		// pointer is dereferenced, so codec is not bypassed by methods promoted to pointer type
+		return encodePrimitive(ec, in)
+	}
+	// then try to check if TextMarshaler is defined for type
 	if implements[encoding.TextMarshaler](in) {
-		return encodeUsingTextMarshaler(in)
+		val, err := encodeUsingTextMarshaler(in)
+		if err == nil && ec.requireUTF8 && !utf8.ValidString(val) {
+			return "", fmt.Errorf("encoding.TextMarshaler of type '%s' returned invalid UTF-8 value %q", in.Type(), val)
+		}
+		return val, err
 	}
 	if isOption(in) {
-		return encodeOption(in)
+		return encodeOption(ec, in)
 	}
-	return encodePrimitive(in)
+	return encodePrimitive(ec, in)
 }
 
-func encodeOption(in reflect.Value) (string, error) {
+func encodeOption(ec *encodeContext, in reflect.Value) (string, error) {
 	isSome := in.Field(isSetFieldIndex)
-	if isSome.Bool() {
-		return encodeUndefined(in.Field(valueFieldIndex))
+	if !isSome.Bool() {
+		return "", nil
+	}
+	return encodeUndefined(ec, optionValue(in))
+}
+
+// isUnsetOption returns true if value is an Option which is not set.
+func isUnsetOption(v reflect.Value) bool {
+	return isOption(v) && !v.Field(isSetFieldIndex).Bool()
+}
+
+// isEmpty checks if value should be omitted by 'omitempty' tag. Option is empty only if it is not set,
+// so e.g. Some(0) is written. Slices and maps are empty if they have no elements, even if they are not nil. Arrays
+// are empty if they have no elements or all their elements are zero.
+func isEmpty(v reflect.Value) bool {
+	if isOption(v) {
+		return !v.Field(isSetFieldIndex).Bool()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Array:
+		return v.Len() == 0 || v.IsZero()
+	}
+	return v.IsZero()
+}
+
+// containsSeparators checks if slice, array or map encoded with default scheme has elements containing separators.
+// Single empty element is reported too, as it is encoded the same way as empty collection.
+func containsSeparators(ec *encodeContext, in reflect.Value) bool {
+	for in.Kind() == reflect.Pointer && !in.IsNil() {
+		in = in.Elem()
+	}
+	if implements[encoding.TextMarshaler](in) {
+		return false
+	}
+	contains := func(v reflect.Value, separators string) bool {
+		s, err := encodeUndefined(ec, v)
+		return err == nil && strings.ContainsAny(s, separators)
+	}
+	switch in.Kind() {
+	case reflect.Slice, reflect.Array:
+		if in.Len() == 1 {
+			if s, err := encodeUndefined(ec, in.Index(0)); err == nil && s == "" {
+				return true
+			}
+		}
+		for i := 0; i < in.Len(); i++ {
+			if contains(in.Index(i), itemSeparator) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := in.MapRange()
+		for iter.Next() {
+			if contains(iter.Key(), itemSeparator+keyValueSeparator) || contains(iter.Value(), itemSeparator+keyValueSeparator) {
+				return true
+			}
+		}
 	}
-	return "", nil
+	return false
 }
 
-func encodeJson(in reflect.Value) (string, error) {
-	val, err := json.Marshal(in.Interface())
+// encodeJson encodes value as JSON. With indent every element is written in new line indented with two spaces.
+func encodeJson(in reflect.Value, indent bool) (string, error) {
+	if raw := dereference(in); raw.IsValid() && raw.Type() == rawJsonType {
+		// json.RawMessage is written verbatim
+		if raw.Len() == 0 {
+			return "", nil
+		}
+		if !json.Valid(raw.Bytes()) {
+			return "", fmt.Errorf("cannot marshal value: invalid raw JSON")
+		}
+		return string(raw.Bytes()), nil
+	}
+	var val []byte
+	var err error
+	if indent {
+		val, err = json.MarshalIndent(in.Interface(), "", jsonIndent)
+	} else {
+		val, err = json.Marshal(in.Interface())
+	}
 	if err != nil {
 		return "", fmt.Errorf("cannot marshal value: [%w]", err)
 	}
 	return string(val), nil
 }
 
+// encodeHex encodes byte slice or array as hex string.
+func encodeHex(in reflect.Value) (string, error) {
+	if in.Kind() == reflect.Pointer {
+		if in.IsNil() {
+			return "", nil
+		}
+		in = in.Elem()
+	}
+	if !isBytes(in.Type()) {
+		return "", fmt.Errorf("hex encoding requires byte slice or array, got '%s'", in.Type())
+	}
+	data := make([]byte, in.Len())
+	reflect.Copy(reflect.ValueOf(data), in)
+	return hex.EncodeToString(data), nil
+}
+
+func encodeBinary(in reflect.Value) (string, error) {
+	if in.Kind() == reflect.Pointer && in.IsNil() {
+		return "", nil
+	}
+	fun := method(in, "MarshalBinary")
+	if !fun.IsValid() || fun.IsZero() {
+		return "", fmt.Errorf("type '%s' or '*%s' doesn't implement encoding.BinaryMarshaler interface", in.Type().Name(), in.Type().Name())
+	}
+	ret := fun.Call([]reflect.Value{})
+	if len(ret) != 2 {
+		return "", fmt.Errorf("expected two return values, got %d", len(ret))
+	}
+	if err, ok := ret[1].Interface().(error); ok {
+		return "", fmt.Errorf("failed to serialize with encoding.BinaryMarshaler interface: [%w]", err)
+	}
+	return base64.StdEncoding.EncodeToString(ret[0].Bytes()), nil
+}
+
 func assignBool(in reflect.Value, out *string) error {
 	*out = strconv.FormatBool(in.Bool())
 	return nil
@@ -111,10 +413,16 @@ func assignFloat(in reflect.Value, out *string, bitSize int) error {
 	return nil
 }
 
-func assignArray(in reflect.Value, out *string) error {
+func assignArray(ec *encodeContext, in reflect.Value, out *string) error {
+	if err := checkStructElem(in.Type(), ec.codecs); err != nil {
+		return err
+	}
 	elems := make([]string, in.Len())
 	for i := 0; i < in.Len(); i++ {
-		v, err := encodeUndefined(in.Index(i))
+		if isUnsetOption(in.Index(i)) {
+			return fmt.Errorf("cannot encode array element at index %d: [%w]", i, errUnsetOptionElem)
+		}
+		v, err := encodeUndefined(ec, in.Index(i))
 		if err != nil {
 			return fmt.Errorf("cannot encode array element at index %d: [%w]", i, err)
 		}
@@ -124,33 +432,51 @@ func assignArray(in reflect.Value, out *string) error {
 	return nil
 }
 
-func assignMap(in reflect.Value, out *string) error {
-	elems := make([]string, in.Len())
+// assignBytes stores bytes of slice or array as raw string.
+func assignBytes(in reflect.Value, out *string) error {
+	b := make([]byte, in.Len())
+	for i := range b {
+		b[i] = byte(in.Index(i).Uint())
+	}
+	*out = string(b)
+	return nil
+}
+
+// assignMap encodes map as list of key-value pairs sorted by encoded keys, so the result is deterministic.
+func assignMap(ec *encodeContext, in reflect.Value, out *string) error {
+	type pair struct{ key, value string }
+	pairs := make([]pair, 0, in.Len())
 	iter := in.MapRange()
-	i := 0
 	for iter.Next() {
 		v := iter.Value()
 		k := iter.Key()
-		ev, err := encodeUndefined(v)
+		if isUnsetOption(v) {
+			return fmt.Errorf("cannot encode map value element: [%w]", errUnsetOptionElem)
+		}
+		ev, err := encodeUndefined(ec, v)
 		if err != nil {
 			return fmt.Errorf("cannot encode map value element: [%w]", err)
 		}
-		ek, err := encodeUndefined(k)
+		ek, err := encodeUndefined(ec, k)
 		if err != nil {
 			return fmt.Errorf("cannot encode map key element: [%w]", err)
 		}
-		elems[i] = strings.Join([]string{ek, ev}, keyValueSeparator)
-		i++
+		pairs = append(pairs, pair{ek, ev})
+	}
+	slices.SortFunc(pairs, func(a, b pair) int { return strings.Compare(a.key, b.key) })
+	elems := make([]string, len(pairs))
+	for i, p := range pairs {
+		elems[i] = p.key + keyValueSeparator + p.value
 	}
 	*out = strings.Join(elems, itemSeparator)
 	return nil
 }
 
-func assignPointer(in reflect.Value, out *string) error {
+func assignPointer(ec *encodeContext, in reflect.Value, out *string) error {
 	if in.IsNil() {
 		return nil
 	}
-	v, err := encodeUndefined(in.Elem())
+	v, err := encodeUndefined(ec, in.Elem())
 	if err != nil {
 		return fmt.Errorf("cannot encode pointer: [%w]", err)
 	}
@@ -158,11 +484,19 @@ func assignPointer(in reflect.Value, out *string) error {
 	return nil
 }
 
-func assignSlice(in reflect.Value, out *string) error {
-	return assignArray(in, out)
+func assignSlice(ec *encodeContext, in reflect.Value, out *string) error {
+	return assignArray(ec, in, out)
 }
 
-func encodePrimitive(in reflect.Value) (out string, err error) {
+func encodePrimitive(ec *encodeContext, in reflect.Value) (out string, err error) {
+	if isBytes(in.Type()) {
+		err = assignBytes(in, &out)
+		return out, err
+	}
+	if in.Type() == metaDurationType {
+		// metav1.Duration is encoded the same way as by Kubernetes API (e.g. "30s")
+		return time.Duration(in.Field(0).Int()).String(), nil
+	}
 	switch in.Kind() {
 	case reflect.Bool:
 		err = assignBool(in, &out)
@@ -191,42 +525,176 @@ func encodePrimitive(in reflect.Value) (out string, err error) {
 	case reflect.Float64:
 		err = assignFloat(in, &out, 64)
 	case reflect.Array:
-		err = assignArray(in, &out)
+		err = assignArray(ec, in, &out)
 	case reflect.Map:
-		err = assignMap(in, &out)
+		err = assignMap(ec, in, &out)
 	case reflect.Pointer:
-		err = assignPointer(in, &out)
+		err = assignPointer(ec, in, &out)
 	case reflect.Slice:
-		err = assignSlice(in, &out)
+		err = assignSlice(ec, in, &out)
 	case reflect.String:
 		out = in.String()
 		err = nil
+	case reflect.Interface:
+		// nil interface is encoded as empty string, otherwise concrete value is encoded.
+		if !in.IsNil() {
+			out, err = encodeUndefined(ec, in.Elem())
+		}
 	default:
-		return "", fmt.Errorf("unsupported type")
+		return "", &UnsupportedTypeError{Type: in.Type()}
+	}
+	return out, err
+}
+
+// encodeTTL encodes time.Time or time.Duration (relative to now) as expiry timestamp.
+func encodeTTL(in reflect.Value, now func() time.Time) (string, error) {
+	if in.Kind() == reflect.Pointer {
+		if in.IsNil() {
+			return "", nil
+		}
+		in = in.Elem()
+	}
+	var expiry time.Time
+	switch in.Type() {
+	case timeType:
+		expiry = in.Interface().(time.Time)
+	case durationType:
+		expiry = now().Add(time.Duration(in.Int()))
+	default:
+		return "", fmt.Errorf("ttl encoding requires time.Time or time.Duration, got '%s'", in.Type())
+	}
+	return expiry.UTC().Format(time.RFC3339), nil
+}
+
+// encodeBase encodes big.Int or integer in given base.
+func encodeBase(in reflect.Value, base int) (string, error) {
+	if in.Kind() == reflect.Pointer {
+		if in.IsNil() {
+			return "", nil
+		}
+		in = in.Elem()
+	}
+	if in.Type() == bigIntType {
+		if in.CanAddr() {
+			in = asWritableValue(in)
+		} else {
+			c := reflect.New(in.Type()).Elem()
+			c.Set(in)
+			in = c
+		}
+		return in.Addr().Interface().(*big.Int).Text(base), nil
+	}
+	switch in.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(in.Int(), base), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(in.Uint(), base), nil
+	}
+	return "", fmt.Errorf("base encoding requires big.Int or integer type, got '%s'", in.Type())
+}
+
+// encodePercent encodes integer from 0-100 range optionally followed by '%' suffix.
+func encodePercent(ec *encodeContext, in reflect.Value, suffix bool) (string, error) {
+	if err := checkPercent(in); err != nil {
+		return "", err
+	}
+	out, err := encodeUndefined(ec, in)
+	if err == nil && suffix && out != "" {
+		out += percentSuffix
 	}
 	return out, err
 }
 
-func encode(in reflect.Value, enc encoder, meta metav1.Object) (string, error) {
+func encode(ec *encodeContext, in reflect.Value, tag *parsedTag) (string, error) {
+	enc, params := ec.defaults.encoding(tag)
 	switch enc {
 	case encoder(undefined):
-		return encodeUndefined(in)
+		if ec.autoJSON && containsSeparators(ec, in) {
+			val, err := encodeJson(in, false)
+			if err != nil {
+				return "", err
+			}
+			return jsonSentinel + val, nil
+		}
+		return encodeUndefined(ec, in)
 	case jsonEnc:
-		return encodeJson(in)
+		return encodeJson(in, params.indent)
+	case binaryEnc:
+		return encodeBinary(in)
+	case hexEnc:
+		return encodeHex(in)
+	case ttlEnc:
+		return encodeTTL(in, ec.now)
+	case percentEnc:
+		return encodePercent(ec, in, false)
+	case percentSuffixEnc:
+		return encodePercent(ec, in, true)
+	case baseEnc:
+		return encodeBase(in, params.base)
+	case boolEnc:
+		return encodeBoolTokens(ec, in, params)
+	case stringerEnc:
+		return encodeStringer(in)
+	case labelSetEnc:
+		return encodeLabelSet(in)
+	case orderedMapEnc:
+		return encodeOrderedMap(ec, in)
+	case humanIntEnc:
+		// underscores are never written, so encoded value is readable by default encoding too
+		return encodeBase(in, 10)
 	case custom:
-		return "", encodeCustom(in, meta)
+		if ec.errorOnNilCustom && in.Kind() == reflect.Pointer && in.IsNil() {
+			return "", fmt.Errorf("nil pointer of type '%s' cannot be serialized with metaser.MetadataMarshaler interface", in.Type())
+		}
+		if ec.overwrite {
+			return "", encodeCustomWritten(ec, in, tag.value)
+		}
+		return "", encodeCustom(in, ec.meta, tag.value)
 	default:
 		return "", fmt.Errorf("unsupported encoding")
 	}
 }
 
-func encodeCustom(out reflect.Value, meta metav1.Object) error {
+// encodeBoolTokens encodes bool, pointer or Option of bool as token of 'bool' encoding. Nil pointer and unset Option
+// are encoded as empty string.
+func encodeBoolTokens(ec *encodeContext, in reflect.Value, params encParams) (string, error) {
+	if t := valueType(in.Type()); t.Kind() != reflect.Bool {
+		return "", fmt.Errorf("bool encoding requires bool type, got '%s'", t)
+	}
+	s, err := encodeUndefined(ec, in)
+	if err != nil {
+		return "", err
+	}
+	switch s {
+	case strconv.FormatBool(true):
+		return params.trueToken, nil
+	case strconv.FormatBool(false):
+		return params.falseToken, nil
+	}
+	return s, nil
+}
+
+// encodeCustom serializes out with metaser.MetadataMarshaler interface. Elements of slices are serialized into keys
+// scoped by field path.
+func encodeCustom(out reflect.Value, meta metav1.Object, field string) error {
 	var fun reflect.Value
 
 	if out.Kind() == reflect.Pointer && out.IsNil() {
 		return nil
 	}
 
+	if out.Kind() == reflect.Slice && !method(out, "MarshalToMetadata").IsValid() {
+		return encodeCustomSlice(out, meta, field)
+	}
+
+	// unset Option is skipped like nil pointer
+	if isOption(out) {
+		if !out.Field(isSetFieldIndex).Bool() {
+			return nil
+		}
+		return encodeCustom(asWritableValue(out.Field(valueFieldIndex)), meta, field)
+	}
+
 	fun = method(out, "MarshalToMetadata")
 	if !fun.IsValid() || fun.IsZero() {
 		return fmt.Errorf("type '%s' or '*%s' doesn't implement metaser.MetadataMarshaler interface", out.Type().Name(), out.Type().Name())
@@ -241,52 +709,304 @@ func encodeCustom(out reflect.Value, meta metav1.Object) error {
 	return nil
 }
 
+// encodeCustomWritten encodes custom field into metadata view containing only keys written by the field and copies
+// them into metadata, marking them as written, so they are not removed by Overwrite.
+func encodeCustomWritten(ec *encodeContext, in reflect.Value, field string) error {
+	view := &metav1.ObjectMeta{
+		Name:        ec.meta.GetName(),
+		Namespace:   ec.meta.GetNamespace(),
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	if err := encodeCustom(in, view, field); err != nil {
+		return err
+	}
+	ec.meta.SetName(view.Name)
+	ec.meta.SetNamespace(view.Namespace)
+	for k, v := range view.Annotations {
+		ec.out.Annotations[k] = v
+		ec.written.Annotations[k] = struct{}{}
+	}
+	for k, v := range view.Labels {
+		ec.out.Labels[k] = v
+		ec.written.Labels[k] = struct{}{}
+	}
+	return nil
+}
+
+func encodeCustomSlice(in reflect.Value, meta metav1.Object, field string) error {
+	annotations := meta.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+		meta.SetAnnotations(annotations)
+	}
+	labels := meta.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+		meta.SetLabels(labels)
+	}
+	clearCustomItems(annotations, field)
+	clearCustomItems(labels, field)
+
+	for i := 0; i < in.Len(); i++ {
+		view := &metav1.ObjectMeta{
+			Name:        meta.GetName(),
+			Namespace:   meta.GetNamespace(),
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		}
+		if err := encodeCustom(in.Index(i), view, field); err != nil {
+			return fmt.Errorf("cannot encode slice element at index %d: [%w]", i, err)
+		}
+		for k, v := range view.Annotations {
+			annotations[customItemKey(field, i, k)] = v
+		}
+		for k, v := range view.Labels {
+			labels[customItemKey(field, i, k)] = v
+		}
+	}
+	return nil
+}
+
 func encodeField(ec *encodeContext, dv *structField) error {
 	var val string
 	var err error
 
-	// do not encoded fields that are marked as 'input only' or 'inline'.
-	// fields within inline field will be encoded by separate calls to encodeField.
-	if dv.tag == nil || dv.tag.dir == in || dv.tag.inline {
+	// do not encoded fields that are marked as 'input only', 'inline' or are read-only ('creationtimestamp',
+	// 'deletiontimestamp', 'generation'). fields within inline field will be encoded by separate calls to encodeField.
+	if dv.tag == nil || dv.tag.dir == in || dv.tag.inline || dv.tag.source == creationTimestamp ||
+		dv.tag.source == deletionTimestamp || dv.tag.source == generation {
 		return nil
 	}
 
-	if dv.tag.omitempty && dv.value.IsZero() {
-		switch dv.tag.source {
-		case label:
-			delete(ec.out.Labels, dv.tag.value)
-		case annotation:
-			delete(ec.out.Annotations, dv.tag.value)
+	if ec.enforceImmutable && dv.tag.immutable {
+		if err = checkImmutable(ec, dv); err != nil {
+			return err
 		}
+	}
+
+	if ec.preserveSetOnce && dv.tag.setOnce && alreadySet(ec, dv.tag) {
 		return nil
 	}
 
+	// omitted annotations and labels are removed, while existing name, namespace and finalizers are left intact
+	// with default value omitempty removes only values equal to the default (see omitsDefault), so zero value is kept
+	if (dv.tag.omitempty && !dv.tag.hasDefault && isEmpty(dv.value)) || (!ec.keepUnsetOptions && isUnsetOption(dv.value)) {
+		keys := []string{dv.tag.value}
+		if dv.tag.isRange {
+			minKey, maxKey := rangeKeys(dv.tag.value)
+			keys = []string{minKey, maxKey}
+		}
+		for _, key := range keys {
+			switch dv.tag.source {
+			case label:
+				delete(ec.out.Labels, key)
+			case annotation:
+				delete(ec.out.Annotations, key)
+			}
+		}
+		if dv.tag.source == provided {
+			return deleteProvided(ec, dv.tag)
+		}
+		return nil
+	}
+
+	if dv.tag.isRange {
+		if dv.tag.source == label {
+			return encodeRange(ec, dv.value, ec.out.Labels, ec.written.Labels, dv.tag)
+		}
+		return encodeRange(ec, dv.value, ec.out.Annotations, ec.written.Annotations, dv.tag)
+	}
+
 	switch dv.tag.source {
 	case name:
-		if val, err = encodePrimitive(dv.value); err == nil {
-			ec.meta.SetName(val)
+		if dv.tag.template != "" {
+			val, err = expandTemplate(ec, dv.tag.template)
+		} else {
+			val, err = encodePrimitive(ec, dv.value)
+		}
+		if err == nil {
+			if err = dv.tag.checkDNS(val); err == nil {
+				ec.meta.SetName(val)
+			}
 		}
 	case namespace:
-		if val, err = encodePrimitive(dv.value); err == nil {
-			ec.meta.SetNamespace(val)
+		if val, err = encodePrimitive(ec, dv.value); err == nil {
+			if err = dv.tag.checkDNS(val); err == nil {
+				ec.meta.SetNamespace(val)
+			}
 		}
 	case label:
-		if val, err = encode(dv.value, dv.tag.enc, ec.meta); err == nil {
+		if val, err = encode(ec, dv.value, dv.tag); err == nil {
+			if err = dv.tag.checkDNS(val); err != nil {
+				return err
+			}
+			if err = dv.tag.checkSize(val); err != nil {
+				return err
+			}
+			if err = checkValue(ec, dv.tag, val); err != nil {
+				return err
+			}
+			if dv.tag.omitsDefault(val) {
+				delete(ec.out.Labels, dv.tag.value)
+				break
+			}
 			ec.out.Labels[dv.tag.value] = val
+			ec.written.Labels[dv.tag.value] = struct{}{}
+			if ec.cleanupAliases {
+				removeAliases(ec.out.Labels, dv.tag)
+			}
 		}
 	case annotation:
-		if val, err = encode(dv.value, dv.tag.enc, ec.meta); err == nil {
+		if val, err = encode(ec, dv.value, dv.tag); err == nil {
+			if err = dv.tag.checkDNS(val); err != nil {
+				return err
+			}
+			if err = dv.tag.checkSize(val); err != nil {
+				return err
+			}
+			if err = checkValue(ec, dv.tag, val); err != nil {
+				return err
+			}
+			if dv.tag.omitsDefault(val) {
+				delete(ec.out.Annotations, dv.tag.value)
+				break
+			}
 			ec.out.Annotations[dv.tag.value] = val
+			ec.written.Annotations[dv.tag.value] = struct{}{}
+			if ec.cleanupAliases {
+				removeAliases(ec.out.Annotations, dv.tag)
+			}
 		}
+	case owner:
+		err = encodeOwner(dv.value, ec.meta, dv.tag.value)
+	case finalizers:
+		err = encodeFinalizers(dv.value, ec.meta)
+	case labelPresence:
+		err = encodeLabelPresence(ec, dv)
+	case provided:
+		err = encodeProvided(ec, dv)
+	case allLabels, allAnnotations:
+		// applied after all other fields, so keys written by them take precedence
+		ec.wholeMaps = append(ec.wholeMaps, *dv)
 	case source(undefined):
-		_, err = encode(dv.value, dv.tag.enc, ec.meta)
+		_, err = encode(ec, dv.value, dv.tag)
 	}
 
 	return err
 }
 
-func appendFieldValues(values []structField, v reflect.Value) ([]structField, error) {
-	v = dereference(v)
+// removeAliases deletes alias keys of tag from out. Alias equal to canonical key is left intact.
+func removeAliases(out map[string]string, tag *parsedTag) {
+	for _, alias := range tag.aliases {
+		if alias != tag.value {
+			delete(out, alias)
+		}
+	}
+}
+
+// encodeLabelPresence writes bool field as label. Label is removed when value equals tag's absent value.
+func encodeLabelPresence(ec *encodeContext, dv *structField) error {
+	if dereference(dv.value).Kind() != reflect.Bool && !(dv.value.Kind() == reflect.Pointer && dv.value.Type().Elem().Kind() == reflect.Bool) {
+		return fmt.Errorf("labelpresence requires bool type, got '%s'", dv.value.Type())
+	}
+	val, err := encodePrimitive(ec, dv.value)
+	if err != nil {
+		return err
+	}
+	if val == "" || val == strconv.FormatBool(dv.tag.absent) {
+		delete(ec.out.Labels, dv.tag.value)
+		return nil
+	}
+	ec.out.Labels[dv.tag.value] = val
+	ec.written.Labels[dv.tag.value] = struct{}{}
+	return nil
+}
+
+// present checks if metadata contains value referenced by tag. Annotations and labels are present if any of their
+// aliases, fallbacks or keys of range exists.
+func present(meta metav1.Object, tag *parsedTag) bool {
+	switch tag.source {
+	case name:
+		return meta.GetName() != ""
+	case namespace:
+		return meta.GetNamespace() != ""
+	case annotation, label:
+		if tag.isRange {
+			minKey, maxKey := rangeKeys(tag.value)
+			values := sourceValues(meta, tag.source)
+			_, minOk := values[minKey]
+			_, maxOk := values[maxKey]
+			return minOk || maxOk
+		}
+		_, _, ok := resolve(meta, tag)
+		return ok
+	case labelPresence:
+		return true
+	case owner:
+		return len(filterOwners(meta.GetOwnerReferences(), tag.value, true)) > 0
+	case finalizers:
+		return len(meta.GetFinalizers()) > 0
+	}
+	return false
+}
+
+// alreadySet checks if key referenced by tag has non-empty value in metadata. The key (and its aliases) is
+// marked as written, so it is not pruned.
+func alreadySet(ec *encodeContext, tag *parsedTag) bool {
+	switch tag.source {
+	case name:
+		return ec.meta.GetName() != ""
+	case namespace:
+		return ec.meta.GetNamespace() != ""
+	case annotation, label:
+		if v, ok := match(sourceValues(ec.meta, tag.source), tag); !ok || v == "" {
+			return false
+		}
+		written := ec.written.Annotations
+		if tag.source == label {
+			written = ec.written.Labels
+		}
+		for _, key := range append([]string{tag.value}, tag.aliases...) {
+			written[key] = struct{}{}
+		}
+		return true
+	}
+	return present(ec.meta, tag)
+}
+
+// checkImmutable returns an error when value of field differs from the one stored in metadata. Value encoded the same
+// way as the stored one is unchanged, which covers types serialized with codecs registered in Encoder. Otherwise the
+// stored value is decoded with default encoding of Encoder and compared with the field.
+func checkImmutable(ec *encodeContext, dv *structField) error {
+	if !present(ec.meta, dv.tag) {
+		return nil
+	}
+	if (dv.tag.source == annotation || dv.tag.source == label) && !dv.tag.isRange {
+		if val, err := encode(ec, dv.value, dv.tag); err == nil && val == lookup(ec.meta, dv.tag) {
+			return nil
+		}
+	}
+	tag := *dv.tag
+	tag.dir = inout
+	cv := reflect.New(dv.value.Type()).Elem()
+	if err := decodeField(&decodeContext{meta: ec.meta, now: ec.now, defaults: ec.defaults, autoJSON: ec.autoJSON}, &tag, cv); err != nil {
+		return fmt.Errorf("unable to decode current value: [%w]", err)
+	}
+	if !equal(dv.value, cv) {
+		return fmt.Errorf("%s '%s': field is immutable", tag.source, tag.value)
+	}
+	return nil
+}
+
+func appendFieldValues(values []structField, v reflect.Value, prefix, path string) ([]structField, error) {
+	// every pointer of the chain is checked, as nil inline struct has no fields to encode
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return values, nil
+		}
+		v = v.Elem()
+	}
 
 	if v.Kind() != reflect.Struct {
 		return values, nil
@@ -297,33 +1017,113 @@ func appendFieldValues(values []structField, v reflect.Value) ([]structField, er
 		if err != nil {
 			return nil, err
 		}
+		if ptag == nil {
+			values = append(values, structField{value: v.Field(i)})
+			continue
+		}
+		if err = markRange(v.Type().Field(i).Type, ptag); err != nil {
+			return nil, err
+		}
+		name := v.Type().Field(i).Name
+		if path != "" {
+			name = path + "." + name
+		}
+		if ptag.source == source(undefined) && ptag.enc == custom {
+			// elements of custom-encoded slices are stored under keys scoped by field path
+			ptag.value = name
+		}
 		values = append(values, structField{
-			value: v.Field(i),
-			tag:   ptag,
+			value:  v.Field(i),
+			tag:    ptag.withPrefix(prefix),
+			prefix: prefix + ptag.prefix,
+			path:   name,
 		})
 	}
 	return values, nil
 }
 
+// removeUnmanaged removes keys which are neither written nor known to the encoded struct. Keys of range fields are
+// '<key>-min' and '<key>-max'.
+func removeUnmanaged(out map[string]string, written map[string]struct{}, managed map[string][]fieldInfo) {
+	known := make(map[string]struct{}, len(managed))
+	for k, infos := range managed {
+		known[k] = struct{}{}
+		for _, info := range infos {
+			if info.tag.isRange {
+				minKey, maxKey := rangeKeys(k)
+				known[minKey], known[maxKey] = struct{}{}, struct{}{}
+			}
+		}
+	}
+	for k := range out {
+		if _, ok := written[k]; ok {
+			continue
+		}
+		if _, ok := known[k]; !ok {
+			delete(out, k)
+		}
+	}
+}
+
+func prune(out map[string]string, written map[string]struct{}, managed map[string][]fieldInfo) {
+	for k, infos := range managed {
+		if _, ok := written[k]; ok {
+			continue
+		}
+		for _, info := range infos {
+			if info.tag.dir != in {
+				delete(out, k)
+				break
+			}
+		}
+	}
+}
+
 // Encode reads data from v and writes it into K8s object metadata.
 //
 // See package documentation for details about serialization.
-func (*Encoder) Encode(v any, meta metav1.Object, options ...EncodeOption) error {
-	var err error
+func (enc *Encoder) Encode(v any, meta metav1.Object, options ...EncodeOption) error {
 	value := reflect.ValueOf(v)
 
 	if value.Kind() != reflect.Pointer {
 		return fmt.Errorf("expected pointer to value")
 	}
 
+	cache, err := loadCache(&enc.cache, value.Type(), enc.codecs)
+	if err != nil {
+		return err
+	}
+
 	ec := &encodeContext{
-		meta: meta,
+		cache:    cache,
+		root:     value,
+		now:      enc.clock(),
+		codecs:   enc.codecs,
+		defaults: enc.defaults,
 	}
+	ec.written.Labels = map[string]struct{}{}
+	ec.written.Annotations = map[string]struct{}{}
 
 	for _, opt := range options {
 		opt(ec)
 	}
 
+	if ec.keyFunc != nil {
+		ec.cache = ec.cache.withKeyFunc(ec.keyFunc)
+	}
+
+	target := meta
+	if ec.validateBeforeWrite {
+		meta = newScratchMeta(target)
+	}
+	ec.meta = meta
+
+	if ec.validateKeys {
+		if err = ec.cache.validateKeys(); err != nil {
+			return fmt.Errorf("invalid keys in struct tags: %w", err)
+		}
+	}
+
 	ec.out.Annotations = meta.GetAnnotations()
 	if ec.out.Annotations == nil {
 		ec.out.Annotations = map[string]string{}
@@ -336,34 +1136,247 @@ func (*Encoder) Encode(v any, meta metav1.Object, options ...EncodeOption) error
 		meta.SetLabels(ec.out.Labels)
 	}
 
-	ec.values, err = appendFieldValues(ec.values, value)
+	ec.values, err = appendFieldValues(ec.values, value, "", "")
 	if err != nil {
 		return err
 	}
 
 	for len(ec.values) > 0 {
+		if ec.ctx != nil && ec.ctx.Err() != nil {
+			return ec.ctx.Err()
+		}
 		v := ec.values[len(ec.values)-1]
 		ec.values = ec.values[:len(ec.values)-1]
-		if err = encodeField(ec, &v); err != nil {
-			return fmt.Errorf("unable to process value: [%w]", err)
+		if ec.keyFunc != nil && v.tag != nil {
+			v.tag = v.tag.withKeyFunc(ec.keyFunc)
+		}
+		if err = withField(encodeField(ec, &v), v.path); err != nil {
+			if !ec.accumulateErrors {
+				return fmt.Errorf("unable to process value: [%w]", err)
+			}
+			ec.fieldErrors = append(ec.fieldErrors, field.TypeInvalid(field.NewPath("metadata").Child(v.tag.source.String()),
+				v.tag.value, err.Error()))
+			recordFieldErrors(&ec.errorFields, ec.fieldErrors, len(ec.fieldErrors)-1, v.path)
 		}
 
 		if v.tag != nil && v.tag.inline {
-			if ec.values, err = appendFieldValues(ec.values, v.value); err != nil {
+			if ec.values, err = appendFieldValues(ec.values, v.value, v.prefix, v.path); err != nil {
 				return err
 			}
 		}
 	}
 
+	if len(ec.fieldErrors) > 0 {
+		return &fieldError{message: "multiple fields errors encountered", fieldErrors: ec.fieldErrors, fields: ec.errorFields}
+	}
+
+	if ec.overwrite {
+		removeUnmanaged(ec.out.Annotations, ec.written.Annotations, ec.cache.AnnotationFastAccess)
+		removeUnmanaged(ec.out.Labels, ec.written.Labels, ec.cache.LabelsFastAccess)
+	}
+
+	if err = encodeWholeMaps(ec); err != nil {
+		return fmt.Errorf("unable to process value: [%w]", err)
+	}
+
+	if ec.pruneManagedKeys {
+		prune(ec.out.Annotations, ec.written.Annotations, ec.cache.AnnotationFastAccess)
+		prune(ec.out.Labels, ec.written.Labels, ec.cache.LabelsFastAccess)
+	}
+
+	if ec.validateBeforeWrite {
+		if err = validateMeta(meta); err != nil {
+			return fmt.Errorf("metadata validation failed: %w", err)
+		}
+		applyMeta(meta, target)
+	}
+
+	return nil
+}
+
+// EncodeContext is like Encode, but checks ctx before every encoded field and returns ctx.Err() when ctx is done.
+// Fields encoded before cancellation are left in metadata, unless ValidateBeforeWrite option is used.
+func (enc *Encoder) EncodeContext(ctx context.Context, v any, meta metav1.Object, options ...EncodeOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return enc.Encode(v, meta, append(options, func(ec *encodeContext) {
+		ec.ctx = ctx
+	})...)
+}
+
+// EncodeToMaps reads data from v and returns encoded labels, annotations, name and namespace
+// without writing them into any K8s object metadata.
+func (enc *Encoder) EncodeToMaps(v any, options ...EncodeOption) (labels, annotations map[string]string, name, namespace string, err error) {
+	scratch := &metav1.ObjectMeta{}
+	if err = enc.Encode(v, scratch, options...); err != nil {
+		return nil, nil, "", "", err
+	}
+	return scratch.Labels, scratch.Annotations, scratch.Name, scratch.Namespace, nil
+}
+
+// Diff encodes v into a copy of meta and returns sorted list of metadata entries, which would be added, changed
+// or removed by encoding. Annotations and labels are listed in "<source>:<key>" form (e.g.
+// "annotation:example.com/mode"), while changed name, namespace, owner references and finalizers are listed as
+// "name:", "namespace:", "owner:" and "finalizers:". meta is not modified. Empty list means that encoding v would not
+// change metadata, so update of the object can be skipped.
+func (enc *Encoder) Diff(v any, meta metav1.Object, options ...EncodeOption) ([]string, error) {
+	scratch := newScratchMeta(meta)
+	if err := enc.Encode(v, scratch, options...); err != nil {
+		return nil, err
+	}
+	changed := append(diffKeys(label, meta.GetLabels(), scratch.Labels),
+		diffKeys(annotation, meta.GetAnnotations(), scratch.Annotations)...)
+	if meta.GetName() != scratch.Name {
+		changed = append(changed, name.String()+keyValueSeparator)
+	}
+	if meta.GetNamespace() != scratch.Namespace {
+		changed = append(changed, namespace.String()+keyValueSeparator)
+	}
+	if !slices.EqualFunc(meta.GetOwnerReferences(), scratch.OwnerReferences, func(a, b metav1.OwnerReference) bool {
+		return reflect.DeepEqual(a, b)
+	}) {
+		changed = append(changed, owner.String()+keyValueSeparator)
+	}
+	if !slices.Equal(meta.GetFinalizers(), scratch.Finalizers) {
+		changed = append(changed, finalizers.String()+keyValueSeparator)
+	}
+	slices.Sort(changed)
+	return changed, nil
+}
+
+// diffKeys returns keys of src which values differ between current and encoded maps.
+func diffKeys(src source, current, encoded map[string]string) []string {
+	var keys []string
+	for k, v := range encoded {
+		if cv, ok := current[k]; !ok || cv != v {
+			keys = append(keys, src.String()+keyValueSeparator+k)
+		}
+	}
+	for k := range current {
+		if _, ok := encoded[k]; !ok {
+			keys = append(keys, src.String()+keyValueSeparator+k)
+		}
+	}
+	return keys
+}
+
+// newScratchMeta returns copy of name, namespace, labels, annotations, owner references and finalizers from meta.
+func newScratchMeta(meta metav1.Object) *metav1.ObjectMeta {
+	scratch := &metav1.ObjectMeta{
+		Name:            meta.GetName(),
+		Namespace:       meta.GetNamespace(),
+		Labels:          make(map[string]string, len(meta.GetLabels())),
+		Annotations:     make(map[string]string, len(meta.GetAnnotations())),
+		OwnerReferences: append([]metav1.OwnerReference(nil), meta.GetOwnerReferences()...),
+		Finalizers:      append([]string(nil), meta.GetFinalizers()...),
+	}
+	for k, v := range meta.GetLabels() {
+		scratch.Labels[k] = v
+	}
+	for k, v := range meta.GetAnnotations() {
+		scratch.Annotations[k] = v
+	}
+	return scratch
+}
+
+// applyMeta writes name, namespace, labels, annotations, owner references and finalizers from src into dst.
+func applyMeta(src, dst metav1.Object) {
+	dst.SetName(src.GetName())
+	dst.SetNamespace(src.GetNamespace())
+	dst.SetLabels(src.GetLabels())
+	dst.SetAnnotations(src.GetAnnotations())
+	dst.SetOwnerReferences(src.GetOwnerReferences())
+	dst.SetFinalizers(src.GetFinalizers())
+}
+
+// checkValue validates encoded label or annotation value when ValidateValues option is used.
+func checkValue(ec *encodeContext, tag *parsedTag, value string) error {
+	if !ec.validateValues {
+		return nil
+	}
+	switch tag.source {
+	case label:
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid label value '%s': %s", value, strings.Join(errs, "; "))
+		}
+	case annotation:
+		if size := len(tag.value) + len(value); size > apivalidation.TotalAnnotationSizeLimitB {
+			return fmt.Errorf("annotation has %d bytes, exceeds limit of %d bytes", size, apivalidation.TotalAnnotationSizeLimitB)
+		}
+	}
+	return nil
+}
+
+func validateMeta(meta metav1.Object) error {
+	var fieldErrors field.ErrorList
+	path := field.NewPath("metadata")
+	if ns := meta.GetNamespace(); ns != "" {
+		for _, msg := range apivalidation.ValidateNamespaceName(ns, false) {
+			fieldErrors = append(fieldErrors, field.Invalid(path.Child(namespaceKey), ns, msg))
+		}
+	}
+	fieldErrors = append(fieldErrors, metav1validation.ValidateLabels(meta.GetLabels(), path.Child("labels"))...)
+	fieldErrors = append(fieldErrors, apivalidation.ValidateAnnotations(meta.GetAnnotations(), path.Child("annotations"))...)
+	if len(fieldErrors) > 0 {
+		return &fieldError{message: fieldErrors.ToAggregate().Error(), fieldErrors: fieldErrors}
+	}
 	return nil
 }
 
+// WithClock sets source of current time used by time-dependent encoders. By default time.Now is used.
+// It can be overridden for single Encode call with EncodeClock option.
+func (enc *Encoder) WithClock(now func() time.Time) *Encoder {
+	enc.now = now
+	return enc
+}
+
+// RegisterCodec sets function used to encode all values of type t with default encoding scheme. It takes precedence
+// over encoding.TextMarshaler and default encoding of t, including elements of slices, arrays and maps and values
+// of pointers and options. Codecs should be registered before the Encoder is used.
+func (enc *Encoder) RegisterCodec(t reflect.Type, codec func(reflect.Value) (string, error)) *Encoder {
+	if enc.codecs == nil {
+		enc.codecs = map[reflect.Type]func(reflect.Value) (string, error){}
+	}
+	enc.codecs[t] = codec
+	return enc
+}
+
+func (enc *Encoder) clock() func() time.Time {
+	if enc.now != nil {
+		return enc.now
+	}
+	return time.Now
+}
+
 // NewEncoder returns new Encoder that writes data into meta.
 func NewEncoder() *Encoder {
 	return &Encoder{}
 }
 
+// NewEncoderWithDefaults returns new Encoder with behavior of fields set by defaults (e.g. DefaultEncoding).
+func NewEncoderWithDefaults(defaults ...DefaultOption) (*Encoder, error) {
+	d, err := newDefaults(defaults)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{defaults: d}, nil
+}
+
 // Marshal reads data from v and writes it into K8s object metadata using default Encoder.
 func Marshal(v any, meta metav1.Object, options ...EncodeOption) error {
 	return NewEncoder().Encode(v, meta, options...)
 }
+
+// Diff returns annotations and labels which would be changed by encoding v into meta using default Encoder.
+// See Encoder.Diff.
+func Diff(v any, meta metav1.Object, options ...EncodeOption) ([]string, error) {
+	return NewEncoder().Diff(v, meta, options...)
+}
+
+// MustMarshal is like Marshal but panics if an error occurs. It is intended for tests and initialization only.
+func MustMarshal(v any, meta metav1.Object, options ...EncodeOption) {
+	if err := Marshal(v, meta, options...); err != nil {
+		panic(err)
+	}
+}
@@ -17,8 +17,15 @@ limitations under the License.
 package metaser
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"reflect"
 	"strconv"
+	"strings"
+	"unsafe"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -237,7 +244,7 @@ var _ = Describe("Encoder", func() {
 				err := Marshal(&s, m)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(m.Annotations).To(HaveKey("testkey"))
-				Expect(m.Annotations["testkey"]).To(BeElementOf([]string{"A:a,B:b", "B:b,A:a"}))
+				Expect(m.Annotations["testkey"]).To(Equal("A:a,B:b"))
 			})
 		})
 		When("encoded struct field have input-only annotation reference", func() {
@@ -251,6 +258,29 @@ var _ = Describe("Encoder", func() {
 				Expect(m.Annotations).ToNot(HaveKey("testkey"))
 			})
 		})
+		When("encoded struct fields with omitempty contain empty non-nil collections", func() {
+			It("should remove annotations from metadata", func() {
+				s := struct {
+					Slice []int          `k8s:"annotation:slice,omitempty"`
+					Map   map[string]int `k8s:"annotation:map,omitempty"`
+					Array [0]int         `k8s:"annotation:array,omitempty"`
+					Zero  [2]int         `k8s:"annotation:zero,omitempty"`
+					Bytes [4]byte        `k8s:"annotation:bytes,omitempty,enc:hex"`
+				}{Slice: []int{}, Map: map[string]int{}}
+				m := &metav1.ObjectMeta{Annotations: map[string]string{"slice": "1", "map": "a:1", "array": "", "zero": "1,2", "bytes": "01020304", "other": "x"}}
+				err := Marshal(&s, m)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.Annotations).To(Equal(map[string]string{"other": "x"}))
+			})
+			It("should write non-zero arrays", func() {
+				s := struct {
+					Array [2]int `k8s:"annotation:array,omitempty"`
+				}{Array: [2]int{0, 1}}
+				m := &metav1.ObjectMeta{}
+				Expect(Marshal(&s, m)).To(Succeed())
+				Expect(m.Annotations).To(Equal(map[string]string{"array": "0,1"}))
+			})
+		})
 	})
 	Context("In case struct tags contains custom field", func() {
 		It("should return be serialized with MetadataMarshaler ", func() {
@@ -272,7 +302,7 @@ var _ = Describe("Encoder", func() {
 	Context("In case struct contains field implementing TextMarshaler", func() {
 		It("should have annotation string matching TextMarshaler", func() {
 			s := struct {
-				MyKey MyStruct6 `k8s:"annotation:test"`
+				MyKey MyStruct6 `k8s:"annotation:test,out"`
 			}{
 				MyKey: MyStruct6{
 					A: []int{1, 3, 6},
@@ -336,6 +366,46 @@ var _ = Describe("Encoder", func() {
 				Expect(m.Annotations).ToNot(HaveKey("test"))
 			})
 		})
+		Context("Option is set to zero value", func() {
+			It("should be serialized", func() {
+				s := struct {
+					MyKey Option[int] `k8s:"annotation:test,omitempty"`
+				}{MyKey: Some(0)}
+				m := &metav1.ObjectMeta{Annotations: map[string]string{}}
+				Expect(Marshal(&s, m)).To(Succeed())
+				Expect(m.Annotations).To(HaveKeyWithValue("test", "0"))
+			})
+		})
+		Context("Option is not set but holds non-zero value", func() {
+			It("should not be serialized even with KeepUnsetOptions", func() {
+				s := struct {
+					MyKey Option[int] `k8s:"annotation:test,omitempty"`
+				}{MyKey: Option[int]{value: 5}}
+				m := &metav1.ObjectMeta{Annotations: map[string]string{"test": "1"}}
+				Expect(Marshal(&s, m, KeepUnsetOptions())).To(Succeed())
+				Expect(m.Annotations).ToNot(HaveKey("test"))
+			})
+		})
+	})
+	Context("In case struct contains Option field without omitempty", func() {
+		type s struct {
+			MyKey Option[int] `k8s:"annotation:test"`
+		}
+		It("should serialize set Option", func() {
+			m := &metav1.ObjectMeta{}
+			Expect(Marshal(&s{MyKey: Some(3)}, m)).To(Succeed())
+			Expect(m.Annotations).To(HaveKeyWithValue("test", "3"))
+		})
+		It("should remove annotation for unset Option", func() {
+			m := &metav1.ObjectMeta{Annotations: map[string]string{"test": "3"}}
+			Expect(Marshal(&s{MyKey: None[int]()}, m)).To(Succeed())
+			Expect(m.Annotations).ToNot(HaveKey("test"))
+		})
+		It("should write empty string for unset Option with KeepUnsetOptions", func() {
+			m := &metav1.ObjectMeta{Annotations: map[string]string{"test": "3"}}
+			Expect(Marshal(&s{MyKey: None[int]()}, m, KeepUnsetOptions())).To(Succeed())
+			Expect(m.Annotations).To(HaveKeyWithValue("test", ""))
+		})
 	})
 	Context("In case MetaObject contains annotation referencing field with omitempty", func() {
 		It("should not be serialized", func() {
@@ -376,3 +446,1165 @@ var _ = Describe("Encoder", func() {
 		})
 	})
 })
+
+var _ = Describe("Encoder with PruneManagedKeys enabled", func() {
+	It("should remove annotation when omitempty field is switched from set to zero", func() {
+		type S struct {
+			MyKey  int    `k8s:"annotation:one,omitempty"`
+			MyKey2 string `k8s:"label:two,omitempty"`
+		}
+		enc := NewEncoder()
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"other": "value"}}
+		err := enc.Encode(&S{MyKey: 1, MyKey2: "test"}, m, PruneManagedKeys())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("one", "1"))
+		Expect(m.Labels).To(HaveKeyWithValue("two", "test"))
+
+		err = enc.Encode(&S{}, m, PruneManagedKeys())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).ToNot(HaveKey("one"))
+		Expect(m.Labels).ToNot(HaveKey("two"))
+		Expect(m.Annotations).To(HaveKeyWithValue("other", "value"))
+	})
+	It("should remove stale keys of fields removed from struct and kept as aliases", func() {
+		type V1 struct {
+			MyKey int `k8s:"annotation:old"`
+		}
+		type V2 struct {
+			MyKey int `k8s:"annotation:new,aliases:old"`
+			In    int `k8s:"annotation:input,in"`
+		}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&V1{MyKey: 1}, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("old", "1"))
+
+		m.Annotations["input"] = "2"
+		err = Marshal(&V2{MyKey: 3}, m, PruneManagedKeys())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).ToNot(HaveKey("old"))
+		Expect(m.Annotations).To(HaveKeyWithValue("new", "3"))
+		Expect(m.Annotations).To(HaveKeyWithValue("input", "2"))
+	})
+	It("should keep stale keys when option is not used", func() {
+		type S struct {
+			MyKey int `k8s:"annotation:new,aliases:old"`
+		}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"old": "1"}}
+		err := Marshal(&S{MyKey: 3}, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("old", "1"))
+	})
+})
+
+var _ = Describe("Encoder with ValidateEncodedKeys enabled", func() {
+	It("should return error and leave metadata untouched when keys are invalid", func() {
+		s := struct {
+			MyKey  string `k8s:"label:a123456789012345678901234567890123456789012345678901234567890123"`
+			MyKey2 string `k8s:"annotation:valid"`
+		}{MyKey: "a", MyKey2: "b"}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&s, m, ValidateEncodedKeys())
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(HaveLen(1))
+		Expect(m.Annotations).To(BeEmpty())
+		Expect(m.Labels).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Encoder with ValidateBeforeWrite enabled", func() {
+	type S struct {
+		Namespace string `k8s:"namespace"`
+		MyKey     string `k8s:"label:one"`
+		MyKey2    string `k8s:"annotation:two"`
+	}
+	It("should write metadata when all values are valid", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"other": "value"}}
+		err := Marshal(&S{Namespace: "ns", MyKey: "a", MyKey2: "b"}, m, ValidateBeforeWrite())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Namespace).To(Equal("ns"))
+		Expect(m.Labels).To(HaveKeyWithValue("one", "a"))
+		Expect(m.Labels).To(HaveKeyWithValue("other", "value"))
+		Expect(m.Annotations).To(HaveKeyWithValue("two", "b"))
+	})
+	It("should not write anything when label value is invalid", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"two": "old"}}
+		err := Marshal(&S{Namespace: "ns", MyKey: "not valid!", MyKey2: "b"}, m, ValidateBeforeWrite())
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(HaveLen(1))
+		Expect(m.Namespace).To(BeEmpty())
+		Expect(m.Labels).To(BeEmpty())
+		Expect(m.Annotations).To(Equal(map[string]string{"two": "old"}))
+	})
+	It("should not write anything when annotations exceed size budget", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{MyKey: "a", MyKey2: strings.Repeat("x", 256*1024)}, m, ValidateBeforeWrite())
+		Expect(err).To(HaveOccurred())
+		Expect(m.Labels).To(BeEmpty())
+		Expect(m.Annotations).To(BeEmpty())
+	})
+	It("should not write anything when namespace is invalid", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{Namespace: "Invalid_NS", MyKey: "a"}, m, ValidateBeforeWrite())
+		Expect(err).To(HaveOccurred())
+		Expect(m.Namespace).To(BeEmpty())
+		Expect(m.Labels).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Encoder EncodeToMaps", func() {
+	type S struct {
+		Name      string    `k8s:"name"`
+		Namespace string    `k8s:"namespace"`
+		MyKey     string    `k8s:"label:one"`
+		MyKey2    int       `k8s:"annotation:two"`
+		Custom    MyStruct5 `k8s:"enc:custom"`
+	}
+	It("should return encoded values without touching the object", func() {
+		m := &metav1.ObjectMeta{
+			Name:        "original",
+			Annotations: map[string]string{"two": "0"},
+		}
+		enc := NewEncoder()
+		s := S{Name: "test", Namespace: "ns", MyKey: "a", MyKey2: 2, Custom: MyStruct5{A: []int{5}}}
+		labels, annotations, name, namespace, err := enc.EncodeToMaps(&s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("test"))
+		Expect(namespace).To(Equal("ns"))
+		Expect(labels).To(Equal(map[string]string{"one": "a"}))
+		Expect(annotations).To(Equal(map[string]string{"two": "2", "a-1": "5"}))
+		Expect(m).To(Equal(&metav1.ObjectMeta{
+			Name:        "original",
+			Annotations: map[string]string{"two": "0"},
+		}))
+	})
+	It("should return error when encoding fails", func() {
+		s := struct {
+			V chan int `k8s:"annotation:v"`
+		}{}
+		_, _, _, _, err := NewEncoder().EncodeToMaps(&s)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Encoder with EnforceImmutable enabled", func() {
+	type S struct {
+		MyKey  int     `k8s:"annotation:one,immutable"`
+		MyKey2 float32 `k8s:"label:two,immutable,omitempty"`
+		MyKey3 string  `k8s:"annotation:three"`
+	}
+	It("should return error when immutable annotation is changed", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"one": "1"}}
+		err := Marshal(&S{MyKey: 2}, m, EnforceImmutable())
+		Expect(err).To(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("one", "1"))
+	})
+	It("should return error when immutable label would be removed", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"two": "2.0"}}
+		err := Marshal(&S{}, m, EnforceImmutable())
+		Expect(err).To(HaveOccurred())
+		Expect(m.Labels).To(HaveKeyWithValue("two", "2.0"))
+	})
+	It("should succeed when immutable values are unchanged", func() {
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{"one": "1", "three": "old"},
+			Labels:      map[string]string{"two": "2.0"},
+		}
+		err := Marshal(&S{MyKey: 1, MyKey2: 2, MyKey3: "new"}, m, EnforceImmutable())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("three", "new"))
+		Expect(m.Labels).To(HaveKeyWithValue("two", "2"))
+	})
+	It("should succeed when immutable values are not present yet", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{MyKey: 1}, m, EnforceImmutable())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("one", "1"))
+	})
+	It("should allow changing immutable values when option is not used", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"one": "1"}}
+		err := Marshal(&S{MyKey: 2}, m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("one", "2"))
+	})
+})
+
+var _ = Describe("Encoder with AccumulateEncodeFieldErrors enabled", func() {
+	It("should return all field errors", func() {
+		s := struct {
+			MyKey  uintptr    `k8s:"annotation:one"`
+			MyKey2 complex64  `k8s:"label:two"`
+			MyKey3 complex128 `k8s:"annotation:three"`
+			MyKey4 string     `k8s:"annotation:four"`
+		}{MyKey4: "test"}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&s, m, AccumulateEncodeFieldErrors())
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(HaveLen(3))
+		Expect(m.Annotations).To(HaveKeyWithValue("four", "test"))
+	})
+	It("should abort on first error by default", func() {
+		s := struct {
+			MyKey  uintptr   `k8s:"annotation:one"`
+			MyKey2 complex64 `k8s:"label:two"`
+		}{}
+		err := Marshal(&s, &metav1.ObjectMeta{})
+		Expect(err).To(HaveOccurred())
+		Expect(GetErrorList(err)).To(BeNil())
+	})
+})
+
+var _ = Describe("Encoder with maxbytes limited fields", func() {
+	type S struct {
+		Map   map[string]int `k8s:"annotation:map,maxbytes:10"`
+		Slice []string       `k8s:"label:slice,maxbytes:4"`
+	}
+	It("should encode values within limit", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Map: map[string]int{"a": 1}, Slice: []string{"x", "y"}}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("map", "a:1"))
+		Expect(m.Labels).To(HaveKeyWithValue("slice", "x,y"))
+	})
+	It("should return error when map exceeds limit", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{Map: map[string]int{"a": 1, "b": 2, "c": 3}}, m)
+		Expect(err).To(MatchError(ContainSubstring("exceeds limit of 10 bytes")))
+		Expect(m.Annotations).ToNot(HaveKey("map"))
+	})
+	It("should return error when slice exceeds limit", func() {
+		Expect(Marshal(&S{Slice: []string{"x", "y", "z"}}, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+	It("should return error for invalid maxbytes tag", func() {
+		Expect(Marshal(&struct {
+			V string `k8s:"annotation:v,maxbytes:-1"`
+		}{}, &metav1.ObjectMeta{})).To(HaveOccurred())
+		Expect(Marshal(&struct {
+			V string `k8s:"name,maxbytes:3"`
+		}{}, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Encoder with CleanupAliases enabled", func() {
+	type S struct {
+		A string `k8s:"annotation:new.io/a,aliases:old.io/a;a"`
+		B string `k8s:"label:b,aliases:b;old-b"`
+		C string `k8s:"annotation:c,in,aliases:old-c"`
+	}
+
+	It("should write canonical keys and remove aliases", func() {
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{"old.io/a": "1", "a": "1", "old-c": "3", "other": "x"},
+			Labels:      map[string]string{"old-b": "2"},
+		}
+		Expect(Marshal(&S{A: "10", B: "20"}, m, CleanupAliases())).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"new.io/a": "10", "old-c": "3", "other": "x"}))
+		Expect(m.Labels).To(Equal(map[string]string{"b": "20"}))
+	})
+	It("should leave aliases intact without the option", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"old.io/a": "1"}}
+		Expect(Marshal(&S{A: "10"}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("old.io/a", "1"))
+		Expect(m.Annotations).To(HaveKeyWithValue("new.io/a", "10"))
+	})
+})
+
+var _ = Describe("Encoder with interface fields", func() {
+	type S struct {
+		I any          `k8s:"annotation:i"`
+		S fmt.Stringer `k8s:"annotation:s,omitempty"`
+	}
+
+	It("should encode concrete int value", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{I: 42}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("i", "42"))
+	})
+	It("should encode concrete string value", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{I: "text"}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("i", "text"))
+		Expect(m.Annotations).ToNot(HaveKey("s"))
+	})
+	It("should encode nil interface as empty string", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("i", ""))
+	})
+	It("should return error when decoding into interface", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"i": "42"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("cannot decode into interface type 'interface {}'")))
+	})
+})
+
+var _ = Describe("Encoder with PreserveSetOnce enabled", func() {
+	type S struct {
+		ID    string `k8s:"annotation:id,setonce"`
+		Owner string `k8s:"label:owner,setonce,aliases:old-owner"`
+		Name  string `k8s:"name,setonce"`
+		Other string `k8s:"annotation:other"`
+	}
+
+	It("should not overwrite existing keys", func() {
+		m := &metav1.ObjectMeta{
+			Name:        "first",
+			Annotations: map[string]string{"id": "1", "other": "a"},
+			Labels:      map[string]string{"old-owner": "x"},
+		}
+		Expect(Marshal(&S{ID: "2", Owner: "y", Name: "second", Other: "b"}, m, PreserveSetOnce(), PruneManagedKeys())).To(Succeed())
+		Expect(m.Name).To(Equal("first"))
+		Expect(m.Annotations).To(Equal(map[string]string{"id": "1", "other": "b"}))
+		Expect(m.Labels).To(Equal(map[string]string{"old-owner": "x"}))
+	})
+	It("should write absent and empty keys", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"id": ""}}
+		Expect(Marshal(&S{ID: "2", Owner: "y", Name: "n"}, m, PreserveSetOnce())).To(Succeed())
+		Expect(m.Name).To(Equal("n"))
+		Expect(m.Annotations).To(HaveKeyWithValue("id", "2"))
+		Expect(m.Labels).To(HaveKeyWithValue("owner", "y"))
+	})
+	It("should overwrite existing keys without the option", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"id": "1"}}
+		Expect(Marshal(&S{ID: "2"}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("id", "2"))
+	})
+})
+
+var _ = Describe("Encoding with base", func() {
+	type S struct {
+		Hex  big.Int  `k8s:"annotation:hex,enc:base:16"`
+		Bin  *big.Int `k8s:"annotation:bin,enc:base:2"`
+		Mask uint8    `k8s:"label:mask,enc:base:2"`
+		Neg  int      `k8s:"label:neg,enc:base:16"`
+	}
+
+	It("should round-trip values in base 16 and base 2", func() {
+		in := S{Hex: *big.NewInt(-255), Bin: big.NewInt(10), Mask: 5, Neg: -26}
+		in.Hex.Lsh(&in.Hex, 64)
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"hex": "-ff0000000000000000", "bin": "1010"}))
+		Expect(m.Labels).To(Equal(map[string]string{"mask": "101", "neg": "-1a"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.Hex.Cmp(&in.Hex)).To(BeZero())
+		Expect(out.Bin.Int64()).To(Equal(int64(10)))
+		Expect(out.Mask).To(Equal(uint8(5)))
+		Expect(out.Neg).To(Equal(-26))
+	})
+	It("should return error for invalid number", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"bin": "102"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("invalid base 2 integer '102'")))
+		m = &metav1.ObjectMeta{Labels: map[string]string{"mask": "-1"}}
+		Expect(Unmarshal(m, &S{})).To(HaveOccurred())
+	})
+	It("should return error for invalid base", func() {
+		Expect(Marshal(&struct {
+			V int `k8s:"annotation:v,enc:base:1"`
+		}{}, &metav1.ObjectMeta{})).To(MatchError(ContainSubstring("invalid base '1'")))
+		Expect(Marshal(&struct {
+			V int `k8s:"annotation:v,enc:base:x"`
+		}{}, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+	It("should return error for unsupported type", func() {
+		Expect(Marshal(&struct {
+			V string `k8s:"annotation:v,enc:base:16"`
+		}{}, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Encoding json.RawMessage", func() {
+	type S struct {
+		Raw    json.RawMessage  `k8s:"annotation:raw,enc:json"`
+		RawPtr *json.RawMessage `k8s:"annotation:rawptr,enc:json,omitempty"`
+	}
+
+	It("should write and read raw JSON verbatim", func() {
+		ptr := json.RawMessage(`"quoted"`)
+		in := S{Raw: json.RawMessage(`{"b": [1, 2],"a":"x,y"}`), RawPtr: &ptr}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"raw": `{"b": [1, 2],"a":"x,y"}`, "rawptr": `"quoted"`}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should write empty raw JSON as empty string", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{}, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"raw": ""}))
+	})
+	It("should return error for invalid raw JSON", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Raw: json.RawMessage(`{"a":`)}, m)).To(MatchError(ContainSubstring("invalid raw JSON")))
+		Expect(m.Annotations).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Encoder with AutoJSONCollections enabled", func() {
+	type S struct {
+		List []string          `k8s:"annotation:list,omitempty"`
+		Map  map[string]string `k8s:"annotation:map,omitempty"`
+		JMap map[string]string `k8s:"annotation:jmap,enc:json,omitempty"`
+	}
+	in := S{
+		List: []string{"a,b", "c"},
+		Map:  map[string]string{"url": "http://x"},
+		JMap: map[string]string{"url": "http://x,y"},
+	}
+
+	It("should round-trip json encoded map with colons and commas", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{JMap: in.JMap}, m)).To(Succeed())
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.JMap).To(Equal(in.JMap))
+	})
+	It("should corrupt values containing separators without the option", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{List: in.List}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("list", "a,b,c"))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.List).To(Equal([]string{"a", "b", "c"}))
+		Expect(Marshal(&S{Map: in.Map}, m)).To(Succeed())
+		Expect(Unmarshal(m, &out)).To(HaveOccurred())
+	})
+	It("should upgrade values containing separators to json", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m, AutoJSONCollections())).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("list", `@json:["a,b","c"]`))
+		Expect(m.Annotations).To(HaveKeyWithValue("map", `@json:{"url":"http://x"}`))
+		out := S{}
+		Expect(Unmarshal(m, &out, DecodeAutoJSONCollections())).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should decode prefix as plain value without decoder option", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"list": "@json:x,y"}}
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.List).To(Equal([]string{"@json:x", "y"}))
+		m.Annotations["list"] = `@json:["a,b"]`
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.List).To(Equal([]string{`@json:["a`, `b"]`}))
+	})
+	It("should keep default encoding for values without separators", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{List: []string{"a", "b"}}, m, AutoJSONCollections())).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("list", "a,b"))
+	})
+})
+
+var _ = Describe("MustMarshal", func() {
+	It("should not panic on success", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(func() {
+			MustMarshal(&struct {
+				A int `k8s:"annotation:a"`
+			}{A: 1}, m)
+		}).ToNot(Panic())
+		Expect(m.Annotations).To(HaveKeyWithValue("a", "1"))
+	})
+	It("should panic on invalid tag", func() {
+		Expect(func() {
+			MustMarshal(&struct {
+				A int `k8s:"annotation:a,unknown:x"`
+			}{}, &metav1.ObjectMeta{})
+		}).To(Panic())
+	})
+})
+
+var _ = Describe("Encoding unsupported types", func() {
+	It("should return UnsupportedTypeError", func() {
+		type Inner struct {
+			Fn func() `k8s:"label:fn"`
+		}
+		err := Marshal(&struct {
+			Inner Inner `k8s:"inline"`
+		}{}, &metav1.ObjectMeta{})
+		var ute *UnsupportedTypeError
+		Expect(errors.As(err, &ute)).To(BeTrue())
+		Expect(ute.Type).To(Equal(reflect.TypeOf(func() {})))
+		Expect(ute.Field).To(Equal("Inner.Fn"))
+		Expect(err).To(MatchError(ContainSubstring("unsupported type 'func()' of field 'Inner.Fn'")))
+	})
+})
+
+var _ = Describe("Encoding with hex", func() {
+	type Hash [4]byte
+	type S struct {
+		Data []byte  `k8s:"annotation:data,enc:hex"`
+		Hash Hash    `k8s:"annotation:hash,enc:hex"`
+		Ptr  *[]byte `k8s:"label:ptr,enc:hex,omitempty"`
+	}
+
+	It("should round-trip byte slices and arrays", func() {
+		ptr := []byte{0xff}
+		in := S{Data: []byte("hi!"), Hash: Hash{0xde, 0xad, 0xbe, 0xef}, Ptr: &ptr}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"data": "686921", "hash": "deadbeef"}))
+		Expect(m.Labels).To(Equal(map[string]string{"ptr": "ff"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return error for invalid hex value", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"data": "xyz"}}, &S{})
+		Expect(err).To(MatchError(ContainSubstring("invalid hex value")))
+		err = Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"hash": "dead"}}, &S{})
+		Expect(err).To(MatchError(ContainSubstring("Expected 4 bytes, got 2")))
+	})
+	It("should return error for non byte types", func() {
+		err := Marshal(&struct {
+			V []int `k8s:"annotation:v,enc:hex"`
+		}{}, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("hex encoding requires byte slice or array, got '[]int'")))
+	})
+})
+
+var _ = Describe("Encoding and decoding with key functions", func() {
+	type Inner struct {
+		Zone string `k8s:"label:zone"`
+	}
+	type S struct {
+		Owner  string `k8s:"annotation:owner,aliases:team"`
+		Tier   string `k8s:"label:tier"`
+		Inner  Inner  `k8s:"inline,prefix:inner-"`
+		Public bool   `k8s:"labelpresence:public"`
+	}
+	prefix := func(source, key string) string {
+		if source == "label" {
+			return "labels.example.com/" + key
+		}
+		return "example.com/" + key
+	}
+
+	It("should write and read transformed keys", func() {
+		in := S{Owner: "alice", Tier: "gold", Inner: Inner{Zone: "a"}, Public: true}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m, EncodeKeyFunc(prefix))).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"example.com/owner": "alice"}))
+		Expect(m.Labels).To(Equal(map[string]string{
+			"labels.example.com/tier":       "gold",
+			"labels.example.com/inner-zone": "a",
+			"labels.example.com/public":     "true",
+		}))
+		out := S{}
+		Expect(Unmarshal(m, &out, DecodeKeyFunc(prefix))).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should transform aliases", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/team": "bob"}}
+		out := S{}
+		Expect(Unmarshal(m, &out, DecodeKeyFunc(prefix))).To(Succeed())
+		Expect(out.Owner).To(Equal("bob"))
+	})
+	It("should not affect calls without the option", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Owner: "alice"}, m, EncodeKeyFunc(prefix))).To(Succeed())
+		m2 := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Owner: "alice"}, m2)).To(Succeed())
+		Expect(m2.Annotations).To(Equal(map[string]string{"owner": "alice"}))
+	})
+	It("should prune transformed keys", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/team": "bob", "team": "x"}}
+		Expect(Marshal(&S{Owner: "alice"}, m, EncodeKeyFunc(prefix), PruneManagedKeys())).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"example.com/owner": "alice", "team": "x"}))
+	})
+})
+
+var _ = Describe("Encoding name and namespace with omitempty", func() {
+	type S struct {
+		Name      string  `k8s:"name,omitempty"`
+		Namespace *string `k8s:"namespace,omitempty"`
+	}
+
+	It("should keep existing name and namespace for zero values", func() {
+		m := &metav1.ObjectMeta{Name: "existing", Namespace: "default"}
+		Expect(Marshal(&S{}, m)).To(Succeed())
+		Expect(m.Name).To(Equal("existing"))
+		Expect(m.Namespace).To(Equal("default"))
+	})
+	It("should overwrite name and namespace with non-zero values", func() {
+		ns := "other"
+		m := &metav1.ObjectMeta{Name: "existing", Namespace: "default"}
+		Expect(Marshal(&S{Name: "new", Namespace: &ns}, m)).To(Succeed())
+		Expect(m.Name).To(Equal("new"))
+		Expect(m.Namespace).To(Equal("other"))
+	})
+	It("should clear name without omitempty", func() {
+		m := &metav1.ObjectMeta{Name: "existing"}
+		Expect(Marshal(&struct {
+			Name string `k8s:"name"`
+		}{}, m)).To(Succeed())
+		Expect(m.Name).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Encoding slices of structs", func() {
+	type Port struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	It("should round-trip slice of structs with json encoding", func() {
+		type S struct {
+			Ports []Port `k8s:"annotation:ports,enc:json"`
+		}
+		in := S{Ports: []Port{{Name: "http", Port: 80}, {Name: "https", Port: 443}}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations["ports"]).To(Equal(`[{"name":"http","port":80},{"name":"https","port":443}]`))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return descriptive error for default encoding", func() {
+		type S struct {
+			Ports []*Port `k8s:"annotation:ports"`
+			Pair  [2]Port `k8s:"annotation:pair"`
+		}
+		err := Marshal(&S{Ports: []*Port{{Name: "http"}}}, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("elements of struct type 'metaser.Port' cannot be serialized with default encoding, use 'enc:json' tag")))
+		Expect(errors.Is(err, ErrUnsupportedType)).To(BeTrue())
+		err = Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"pair": "a,b"}}, &S{})
+		Expect(err).To(MatchError(ContainSubstring("elements of struct type 'metaser.Port'")))
+	})
+	It("should use registered codecs for elements", func() {
+		type S struct {
+			Ports []Port  `k8s:"annotation:ports"`
+			Pair  [2]Port `k8s:"annotation:pair"`
+		}
+		enc := NewEncoder().RegisterCodec(reflect.TypeOf(Port{}), func(in reflect.Value) (string, error) {
+			p := in.Interface().(Port)
+			return fmt.Sprintf("%s:%d", p.Name, p.Port), nil
+		})
+		dec := NewDecoder().RegisterCodec(reflect.TypeOf(Port{}), func(out reflect.Value, in string) error {
+			p := Port{}
+			if _, err := fmt.Sscanf(strings.Replace(in, ":", " ", 1), "%s %d", &p.Name, &p.Port); err != nil {
+				return err
+			}
+			out.Set(reflect.ValueOf(p))
+			return nil
+		})
+		in := S{Ports: []Port{{Name: "http", Port: 80}, {Name: "https", Port: 443}}, Pair: [2]Port{{Name: "a", Port: 1}, {Name: "b", Port: 2}}}
+		m := &metav1.ObjectMeta{}
+		Expect(enc.Encode(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"ports": "http:80,https:443", "pair": "a:1,b:2"}))
+		out := S{}
+		Expect(dec.Decode(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+})
+
+// upperID implements text marshaling, which is overridden by registered codecs.
+type upperID string
+
+func (id upperID) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(string(id))), nil
+}
+
+func (id *upperID) UnmarshalText(data []byte) error {
+	*id = upperID(strings.ToLower(string(data)))
+	return nil
+}
+
+var _ = Describe("Encoding and decoding with registered codecs", func() {
+	type S struct {
+		ID     upperID         `k8s:"annotation:id"`
+		IDs    []upperID       `k8s:"annotation:ids"`
+		Ptr    *upperID        `k8s:"label:ptr"`
+		Option Option[upperID] `k8s:"label:option"`
+	}
+	enc := NewEncoder().RegisterCodec(reflect.TypeOf(upperID("")), func(in reflect.Value) (string, error) {
+		return "id-" + in.String(), nil
+	})
+	dec := NewDecoder().RegisterCodec(reflect.TypeOf(upperID("")), func(out reflect.Value, in string) error {
+		id, ok := strings.CutPrefix(in, "id-")
+		if !ok {
+			return fmt.Errorf("invalid id '%s'", in)
+		}
+		out.SetString(id)
+		return nil
+	})
+
+	It("should take precedence over TextMarshaler", func() {
+		ptr := upperID("c")
+		in := S{ID: "a", IDs: []upperID{"a", "b"}, Ptr: &ptr, Option: Some(upperID("d"))}
+		m := &metav1.ObjectMeta{}
+		Expect(enc.Encode(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"id": "id-a", "ids": "id-a,id-b"}))
+		Expect(m.Labels).To(Equal(map[string]string{"ptr": "id-c", "option": "id-d"}))
+		out := S{}
+		Expect(dec.Decode(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return codec errors", func() {
+		err := dec.Decode(&metav1.ObjectMeta{Annotations: map[string]string{"id": "a"}}, &S{})
+		Expect(err).To(MatchError(ContainSubstring("invalid id 'a'")))
+	})
+	It("should not affect other encoders", func() {
+		m := &metav1.ObjectMeta{}
+		ptr := upperID("c")
+		Expect(Marshal(&S{ID: "a", Ptr: &ptr}, m)).To(Succeed())
+		Expect(m.Annotations["id"]).To(Equal("A"))
+		Expect(m.Labels["ptr"]).To(Equal("C"))
+	})
+	It("should accept unchanged immutable field", func() {
+		type I struct {
+			ID upperID `k8s:"annotation:id,immutable"`
+		}
+		m := &metav1.ObjectMeta{}
+		Expect(enc.Encode(&I{ID: "a"}, m)).To(Succeed())
+		Expect(enc.Encode(&I{ID: "a"}, m, EnforceImmutable())).To(Succeed())
+		Expect(enc.Encode(&I{ID: "b"}, m, EnforceImmutable())).To(MatchError(ContainSubstring("field is immutable")))
+	})
+})
+
+var _ = Describe("Direction of custom-encoded fields", func() {
+	It("should skip encoding of field marked as in", func() {
+		s := struct {
+			Item MyItem `k8s:"enc:custom,in"`
+		}{Item: MyItem{Name: "a", Count: 1}}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"name": "old"}}
+		Expect(Marshal(&s, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"name": "old"}))
+		Expect(m.Labels).To(BeEmpty())
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"name": "b"}, Labels: map[string]string{"count": "2"}}, &s)).To(Succeed())
+		Expect(s.Item).To(Equal(MyItem{Name: "b", Count: 2}))
+	})
+	It("should skip decoding of field marked as out", func() {
+		s := struct {
+			Item MyItem `k8s:"enc:custom,out"`
+		}{Item: MyItem{Name: "a", Count: 1}}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"name": "b"}, Labels: map[string]string{"count": "2"}}, &s)).To(Succeed())
+		Expect(s.Item).To(Equal(MyItem{Name: "a", Count: 1}))
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&s, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"name": "a"}))
+		Expect(m.Labels).To(Equal(map[string]string{"count": "1"}))
+	})
+})
+
+var _ = Describe("Round trip of edge values", func() {
+	It("should decode empty map", func() {
+		s := struct {
+			M map[string]int `k8s:"annotation:m"`
+		}{}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&s, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("m", ""))
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.M).To(BeEmpty())
+	})
+	It("should keep single empty element with AutoJSONCollections", func() {
+		s := struct {
+			L []string `k8s:"annotation:l"`
+		}{L: []string{""}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&s, m, AutoJSONCollections())).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("l", `@json:[""]`))
+		s.L = nil
+		Expect(Unmarshal(m, &s, DecodeAutoJSONCollections())).To(Succeed())
+		Expect(s.L).To(Equal([]string{""}))
+	})
+	It("should round-trip float32 extremes", func() {
+		type S struct {
+			Max      float32 `k8s:"annotation:max"`
+			Smallest float32 `k8s:"annotation:smallest"`
+			Third    float32 `k8s:"annotation:third"`
+		}
+		in := S{Max: math.MaxFloat32, Smallest: math.SmallestNonzeroFloat32, Third: 1.0 / 3}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+})
+
+var _ = Describe("Building cache for fields of unsupported kinds", func() {
+	It("should return error naming tagged chan field before encoding", func() {
+		type Inner struct {
+			Ch chan int `k8s:"annotation:ch"`
+		}
+		s := struct {
+			Name  string `k8s:"name"`
+			Inner Inner  `k8s:"inline"`
+		}{Name: "obj"}
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&s, m)
+		var ute *UnsupportedTypeError
+		Expect(errors.As(err, &ute)).To(BeTrue())
+		Expect(ute.Field).To(Equal("Inner.Ch"))
+		Expect(err).To(MatchError("unsupported type 'chan int' of field 'Inner.Ch'"))
+		Expect(m.Name).To(BeEmpty())
+	})
+	It("should detect unsupported kinds behind pointers, options and collections", func() {
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &struct {
+			V *Option[[]func()] `k8s:"label:v"`
+		}{})).To(MatchError("unsupported type 'func()' of field 'V'"))
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &struct {
+			V map[string]unsafe.Pointer `k8s:"annotation:v"`
+		}{})).To(MatchError(ContainSubstring("unsupported type 'unsafe.Pointer'")))
+	})
+	It("should ignore untagged fields of unsupported kinds", func() {
+		s := struct {
+			Name string `k8s:"name"`
+			Done chan struct{}
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Name: "obj"}, &s)).To(Succeed())
+		Expect(s.Name).To(Equal("obj"))
+	})
+})
+
+var _ = Describe("Encoding maps with default scheme", func() {
+	It("should sort items by encoded keys", func() {
+		s := struct {
+			Ints  map[int]string `k8s:"annotation:ints"`
+			Names map[string]int `k8s:"label:names"`
+		}{
+			Ints:  map[int]string{10: "j", 2: "b", -1: "z", 1: "a"},
+			Names: map[string]int{"b": 1, "a1": 2, "a": 3, "c": 4, "A": 5},
+		}
+		for i := 0; i < 10; i++ {
+			m := &metav1.ObjectMeta{}
+			Expect(Marshal(&s, m)).To(Succeed())
+			Expect(m.Annotations["ints"]).To(Equal("-1:z,1:a,10:j,2:b"))
+			Expect(m.Labels["names"]).To(Equal("A:5,a:3,a1:2,b:1,c:4"))
+		}
+	})
+})
+
+var _ = Describe("Collections of Option with default encoding", func() {
+	type S struct {
+		Arr [3]Option[int]         `k8s:"annotation:arr"`
+		Map map[string]Option[int] `k8s:"annotation:map"`
+	}
+
+	It("should round-trip set elements", func() {
+		in := S{Arr: [3]Option[int]{Some(1), Some(0), Some(3)}, Map: map[string]Option[int]{"a": Some(5)}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("arr", "1,0,3"))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		for i := range out.Arr {
+			Expect(out.Arr[i].IsSet()).To(BeTrue())
+		}
+		Expect(out).To(Equal(in))
+	})
+	It("should return an error for unset array element", func() {
+		in := S{Arr: [3]Option[int]{Some(1), None[int](), Some(3)}}
+		err := Marshal(&in, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("array element at index 1")))
+		Expect(err).To(MatchError(ContainSubstring("unset Option cannot be an element")))
+	})
+	It("should return an error for unset map value", func() {
+		in := S{Arr: [3]Option[int]{Some(1), Some(2), Some(3)}, Map: map[string]Option[int]{"a": None[int]()}}
+		Expect(Marshal(&in, &metav1.ObjectMeta{})).To(MatchError(ContainSubstring("unset Option cannot be an element")))
+	})
+	It("should return an error when decoding empty element", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"arr": "1,,3"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("unable to decode array index 1")))
+	})
+})
+
+var _ = Describe("Encoder with nil inline struct pointer", func() {
+	type Inner struct {
+		A string `k8s:"annotation:a"`
+		B int    `k8s:"label:b"`
+	}
+	type S struct {
+		Inner *Inner `k8s:"inline,prefix:inner-"`
+		X     string `k8s:"annotation:x"`
+	}
+
+	It("should skip fields of nil inline struct", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{X: "1"}, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"x": "1"}))
+		Expect(m.Labels).To(BeEmpty())
+	})
+	It("should encode fields of non-nil inline struct", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Inner: &Inner{A: "a", B: 2}}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("inner-a", "a"))
+		Expect(m.Labels).To(HaveKeyWithValue("inner-b", "2"))
+	})
+})
+
+var _ = Describe("Encoder with nested nil inline struct pointers", func() {
+	type B struct {
+		X string `k8s:"annotation:x"`
+	}
+	type A struct {
+		B *B     `k8s:"inline"`
+		Y string `k8s:"annotation:y"`
+	}
+	type S struct {
+		A  *A  `k8s:"inline"`
+		PP **B `k8s:"inline,prefix:pp-"`
+	}
+
+	It("should skip nil outer pointer", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{}, m)).To(Succeed())
+		Expect(m.Annotations).To(BeEmpty())
+	})
+	It("should skip nil inner pointer", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{A: &A{Y: "y"}}, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"y": "y"}))
+	})
+	It("should skip pointer to nil pointer", func() {
+		m := &metav1.ObjectMeta{}
+		var b *B
+		Expect(Marshal(&S{PP: &b}, m)).To(Succeed())
+		Expect(m.Annotations).To(BeEmpty())
+	})
+	It("should encode fields of fully allocated chain", func() {
+		m := &metav1.ObjectMeta{}
+		b := &B{X: "z"}
+		Expect(Marshal(&S{A: &A{B: &B{X: "x"}}, PP: &b}, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"x": "x", "y": "", "pp-x": "z"}))
+	})
+})
+
+var _ = Describe("Encoder with nil custom-encoded pointer", func() {
+	type S struct {
+		Custom *MyStruct5 `k8s:"enc:custom"`
+	}
+
+	It("should skip nil pointer by default", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{}}
+		Expect(Marshal(&S{}, m)).To(Succeed())
+		Expect(m.Annotations).To(BeEmpty())
+	})
+	It("should return an error for nil pointer with ErrorOnNilCustom", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{}}
+		err := Marshal(&S{}, m, ErrorOnNilCustom())
+		Expect(err).To(MatchError(ContainSubstring("nil pointer of type '*metaser.MyStruct5'")))
+	})
+	It("should encode non-nil pointer with ErrorOnNilCustom", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{}}
+		Expect(Marshal(&S{Custom: &MyStruct5{A: []int{7}}}, m, ErrorOnNilCustom())).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("a-1", "7"))
+	})
+})
+
+var _ = Describe("Encoder with ValidateValues enabled", func() {
+	type S struct {
+		Zones []string `k8s:"label:zones"`
+		Note  string   `k8s:"annotation:note"`
+	}
+	long := []string{strings.Repeat("a", 40), strings.Repeat("b", 40)}
+
+	It("should return an error for over-length label value", func() {
+		m := &metav1.ObjectMeta{}
+		err := Marshal(&S{Zones: long}, m, ValidateValues())
+		Expect(err).To(MatchError(ContainSubstring("invalid label value")))
+		Expect(err).To(MatchError(ContainSubstring("must be no more than 63 characters")))
+	})
+	It("should return an error for invalid label value syntax", func() {
+		Expect(Marshal(&S{Zones: []string{"a", "b"}}, &metav1.ObjectMeta{}, ValidateValues())).To(MatchError(ContainSubstring("invalid label value 'a,b'")))
+	})
+	It("should return an error for too large annotation value", func() {
+		err := Marshal(&S{Note: strings.Repeat("x", 256*1024)}, &metav1.ObjectMeta{}, ValidateValues())
+		Expect(err).To(MatchError(ContainSubstring("exceeds limit of 262144 bytes")))
+	})
+	It("should accumulate field errors", func() {
+		err := Marshal(&S{Zones: long, Note: strings.Repeat("x", 256*1024)}, &metav1.ObjectMeta{}, ValidateValues(), AccumulateEncodeFieldErrors())
+		Expect(GetErrorList(err)).To(HaveLen(2))
+	})
+	It("should not validate values without the option", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Zones: long}, m)).To(Succeed())
+		Expect(m.Labels).To(HaveKeyWithValue("zones", strings.Join(long, ",")))
+	})
+})
+
+var _ = Describe("Encoder with repeated Encode calls into the same metadata", func() {
+	type A struct {
+		Shared string `k8s:"annotation:shared"`
+		OnlyA  string `k8s:"annotation:only-a"`
+		Tier   string `k8s:"label:tier"`
+	}
+	type B struct {
+		Shared string            `k8s:"annotation:shared"`
+		OnlyB  string            `k8s:"annotation:only-b"`
+		Input  string            `k8s:"label:input,in"`
+		Extra  map[string]string `k8s:"annotations"`
+	}
+	var m *metav1.ObjectMeta
+
+	BeforeEach(func() {
+		m = &metav1.ObjectMeta{Labels: map[string]string{"input": "x"}}
+		Expect(Marshal(&A{Shared: "a", OnlyA: "a", Tier: "gold"}, m)).To(Succeed())
+	})
+
+	It("should preserve keys of other struct by default", func() {
+		Expect(Marshal(&B{Shared: "b", OnlyB: "b"}, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"shared": "b", "only-a": "a", "only-b": "b"}))
+		Expect(m.Labels).To(Equal(map[string]string{"input": "x", "tier": "gold"}))
+	})
+	It("should preserve keys of other struct with disabled overwrite", func() {
+		Expect(Marshal(&B{Shared: "b", OnlyB: "b"}, m, Overwrite(false))).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("only-a", "a"))
+	})
+	It("should remove keys not managed by encoded struct with enabled overwrite", func() {
+		Expect(Marshal(&B{Shared: "b", OnlyB: "b", Extra: map[string]string{"extra": "e"}}, m, Overwrite(true))).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"shared": "b", "only-b": "b", "extra": "e"}))
+		Expect(m.Labels).To(Equal(map[string]string{"input": "x"}))
+	})
+	It("should keep keys written by custom and range fields with enabled overwrite", func() {
+		type C struct {
+			C     *MyStruct5  `k8s:"enc:custom"`
+			Items []MyStruct5 `k8s:"enc:custom"`
+			R     Range[int]  `k8s:"annotation:r"`
+			In    Range[int]  `k8s:"annotation:in,in"`
+		}
+		m.Annotations["in-min"], m.Annotations["in-max"] = "1", "2"
+		in := C{C: &MyStruct5{A: []int{7}}, Items: []MyStruct5{{A: []int{8}}}, R: Range[int]{Min: 1, Max: 3}}
+		for range 2 {
+			Expect(Marshal(&in, m, Overwrite(true))).To(Succeed())
+			Expect(m.Annotations).To(Equal(map[string]string{"a-1": "7", "Items.item-0-a-1": "8", "r-min": "1",
+				"r-max": "3", "in-min": "1", "in-max": "2"}))
+		}
+		Expect(m.Labels).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Fields with indented json encoding", func() {
+	type Config struct {
+		Replicas int      `json:"replicas"`
+		Zones    []string `json:"zones"`
+	}
+	type S struct {
+		Config Config `k8s:"annotation:config,enc:json:indent"`
+	}
+
+	It("should write multi-line JSON and round-trip it", func() {
+		in := S{Config: Config{Replicas: 2, Zones: []string{"a", "b"}}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations["config"]).To(Equal("{\n  \"replicas\": 2,\n  \"zones\": [\n    \"a\",\n    \"b\"\n  ]\n}"))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should decode compact JSON", func() {
+		out := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"config": `{"replicas":1,"zones":["x"]}`}}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.Config).To(Equal(Config{Replicas: 1, Zones: []string{"x"}}))
+	})
+	It("should reject unknown json parameter", func() {
+		err := Marshal(&struct {
+			A int `k8s:"annotation:a,enc:json:pretty"`
+		}{}, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("invalid encoding value")))
+	})
+})
+
+var _ = Describe("Diff", func() {
+	type S struct {
+		Mode  string `k8s:"annotation:mode"`
+		Tier  string `k8s:"label:tier,omitempty"`
+		Count int    `k8s:"annotation:count"`
+	}
+	var m *metav1.ObjectMeta
+
+	BeforeEach(func() {
+		m = &metav1.ObjectMeta{
+			Annotations: map[string]string{"mode": "on", "count": "1", "other": "x"},
+			Labels:      map[string]string{"tier": "gold"},
+		}
+	})
+
+	It("should return empty list when nothing changed", func() {
+		changed, err := Diff(&S{Mode: "on", Tier: "gold", Count: 1}, m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeEmpty())
+	})
+	It("should report added and changed keys", func() {
+		m.Annotations = map[string]string{"mode": "on"}
+		changed, err := Diff(&S{Mode: "off", Tier: "gold", Count: 2}, m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(Equal([]string{"annotation:count", "annotation:mode"}))
+	})
+	It("should report removed keys", func() {
+		changed, err := Diff(&S{Mode: "on", Count: 1}, m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(Equal([]string{"label:tier"}))
+	})
+	It("should report changed name, namespace, owner references and finalizers", func() {
+		type M struct {
+			Name       string                `k8s:"name"`
+			Namespace  string                `k8s:"namespace"`
+			Owner      metav1.OwnerReference `k8s:"owner"`
+			Finalizers []string              `k8s:"finalizers"`
+			Mode       string                `k8s:"annotation:mode"`
+		}
+		in := M{Name: "a", Namespace: "ns", Owner: metav1.OwnerReference{Kind: "Deployment", Name: "d", UID: "1"},
+			Finalizers: []string{"example.com/f"}, Mode: "on"}
+		Expect(Marshal(&in, m)).To(Succeed())
+		changed, err := Diff(&in, m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeEmpty())
+		changed, err = Diff(&M{Name: "b", Namespace: "other", Owner: metav1.OwnerReference{Kind: "Deployment", Name: "e",
+			UID: "2"}, Mode: "on"}, m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(Equal([]string{"finalizers:", "name:", "namespace:", "owner:"}))
+	})
+	It("should not modify metadata", func() {
+		_, err := Diff(&S{Mode: "off"}, m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Annotations).To(HaveKeyWithValue("mode", "on"))
+	})
+})
+
+// rawText implements text marshaling returning bytes verbatim, which may be invalid UTF-8.
+type rawText []byte
+
+func (t rawText) MarshalText() ([]byte, error) {
+	return t, nil
+}
+
+var _ = Describe("Encoder with RequireUTF8 enabled", func() {
+	type S struct {
+		Text rawText `k8s:"annotation:text,out"`
+	}
+
+	It("should return an error for invalid UTF-8 value", func() {
+		err := Marshal(&S{Text: rawText{0xff, 0xfe}}, &metav1.ObjectMeta{}, RequireUTF8())
+		Expect(err).To(MatchError(ContainSubstring("encoding.TextMarshaler of type 'metaser.rawText' returned invalid UTF-8 value")))
+	})
+	It("should encode valid UTF-8 value", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Text: rawText("zażółć")}, m, RequireUTF8())).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("text", "zażółć"))
+	})
+	It("should write invalid UTF-8 value verbatim by default", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Text: rawText{0xff}}, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("text", "\xff"))
+	})
+})
+
+var _ = Describe("Encoder with custom-encoded Option", func() {
+	type S struct {
+		Custom Option[MyStruct5] `k8s:"enc:custom"`
+	}
+
+	It("should encode set Option with CustomMarshaler", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{}}
+		Expect(Marshal(&S{Custom: Some(MyStruct5{A: []int{7, 8}})}, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"a-1": "7", "a-2": "8"}))
+	})
+	It("should skip unset Option", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{}}
+		Expect(Marshal(&S{}, m, KeepUnsetOptions())).To(Succeed())
+		Expect(m.Annotations).To(BeEmpty())
+	})
+})
@@ -0,0 +1,73 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Envelope is a type storing versioned data in a single annotation or label. It is serialized as JSON object
+// with following syntax: {"version":<Version>,"data":<Data serialized as JSON>}.
+type Envelope[T any] struct {
+	Version int
+	Data    T
+}
+
+// EnvelopeMigrator may be implemented by pointer to type stored in Envelope to convert data stored
+// in older versions. Migrate is called during decoding with version and JSON data read from metadata,
+// before they are unmarshaled into the type. It returns migrated version and data.
+type EnvelopeMigrator interface {
+	Migrate(version int, data json.RawMessage) (int, json.RawMessage, error)
+}
+
+type envelopeWire struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// MarshalText implements encoding.TextMarshaler interface.
+func (e Envelope[T]) MarshalText() ([]byte, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal envelope data: [%w]", err)
+	}
+	return json.Marshal(envelopeWire{Version: e.Version, Data: data})
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+func (e *Envelope[T]) UnmarshalText(text []byte) error {
+	var w envelopeWire
+	if err := json.Unmarshal(text, &w); err != nil {
+		return fmt.Errorf("cannot unmarshal envelope: [%w]", err)
+	}
+	var data T
+	if m, ok := any(&data).(EnvelopeMigrator); ok {
+		version, migrated, err := m.Migrate(w.Version, w.Data)
+		if err != nil {
+			return fmt.Errorf("cannot migrate envelope data from version %d: [%w]", w.Version, err)
+		}
+		w.Version, w.Data = version, migrated
+	}
+	if len(w.Data) > 0 {
+		if err := json.Unmarshal(w.Data, &data); err != nil {
+			return fmt.Errorf("cannot unmarshal envelope data: [%w]", err)
+		}
+	}
+	e.Version, e.Data = w.Version, data
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type settingsV2 struct {
+	Replicas int    `json:"replicas"`
+	Mode     string `json:"mode"`
+}
+
+// Migrate converts version 1 data ({"count":<n>}) into version 2.
+func (s *settingsV2) Migrate(version int, data json.RawMessage) (int, json.RawMessage, error) {
+	switch version {
+	case 1:
+		v1 := struct {
+			Count int `json:"count"`
+		}{}
+		if err := json.Unmarshal(data, &v1); err != nil {
+			return 0, nil, err
+		}
+		data, err := json.Marshal(settingsV2{Replicas: v1.Count, Mode: "default"})
+		return 2, data, err
+	case 2:
+		return version, data, nil
+	}
+	return 0, nil, errors.New("unsupported version")
+}
+
+var _ = Describe("Envelope", func() {
+	type S struct {
+		Settings Envelope[settingsV2] `k8s:"annotation:settings"`
+		Plain    *Envelope[[]string]  `k8s:"annotation:plain"`
+	}
+
+	It("should round-trip versioned data", func() {
+		in := S{
+			Settings: Envelope[settingsV2]{Version: 2, Data: settingsV2{Replicas: 3, Mode: "fast"}},
+			Plain:    &Envelope[[]string]{Version: 7, Data: []string{"a", "b"}},
+		}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(HaveKeyWithValue("settings", `{"version":2,"data":{"replicas":3,"mode":"fast"}}`))
+		Expect(m.Annotations).To(HaveKeyWithValue("plain", `{"version":7,"data":["a","b"]}`))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should migrate data stored in older version", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"settings": `{"version":1,"data":{"count":5}}`}}
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.Settings).To(Equal(Envelope[settingsV2]{Version: 2, Data: settingsV2{Replicas: 5, Mode: "default"}}))
+	})
+	It("should return error when migration fails", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"settings": `{"version":9,"data":{}}`}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("cannot migrate envelope data from version 9")))
+	})
+	It("should return error for malformed envelope", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"plain": `["a"]`}}
+		Expect(Unmarshal(m, &S{})).To(HaveOccurred())
+	})
+})
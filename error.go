@@ -2,24 +2,96 @@ package metaser
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
-type decodeError struct {
+type fieldError struct {
 	message     string
 	fieldErrors field.ErrorList
+	// fields maps dot separated paths of struct fields to their errors.
+	fields map[string]*field.Error
 }
 
-func (de *decodeError) Error() string {
-	return de.message
+// FieldErrorLookup is implemented by errors returned by Decoder and Encoder with accumulated field errors
+// (see AccumulateFieldErrors and AccumulateEncodeFieldErrors). It can be extracted with errors.As.
+type FieldErrorLookup interface {
+	error
+	// ForField returns error of struct field with dot separated path of Go field names (e.g. "Inner.Replicas")
+	// or nil if the field has no error.
+	ForField(path string) *field.Error
 }
 
-// GetErrorList gets field.ErrorList type from underlying error.
+// ForField returns error of struct field with given path or nil.
+func (fe *fieldError) ForField(path string) *field.Error {
+	return fe.fields[path]
+}
+
+// recordFieldErrors maps field errors accumulated after index n to struct field with given path.
+func recordFieldErrors(fields *map[string]*field.Error, list field.ErrorList, n int, path string) {
+	if len(list) <= n {
+		return
+	}
+	if *fields == nil {
+		*fields = map[string]*field.Error{}
+	}
+	if _, ok := (*fields)[path]; !ok {
+		(*fields)[path] = list[n]
+	}
+}
+
+func (fe *fieldError) Error() string {
+	return fe.message
+}
+
+// ErrUnsupportedType is wrapped by errors returned when value of unsupported type or encoding is used.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// UnsupportedTypeError describes type which cannot be encoded or decoded.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+	// Field is dot separated path of struct field containing the value, empty if unknown.
+	Field string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s '%s' of field '%s'", ErrUnsupportedType, e.Type, e.Field)
+	}
+	return fmt.Sprintf("%s '%s'", ErrUnsupportedType, e.Type)
+}
+
+func (e *UnsupportedTypeError) Unwrap() error {
+	return ErrUnsupportedType
+}
+
+// withField sets field of UnsupportedTypeError wrapped by err if it is not set yet.
+func withField(err error, name string) error {
+	var ute *UnsupportedTypeError
+	if errors.As(err, &ute) && ute.Field == "" {
+		ute.Field = name
+	}
+	return err
+}
+
+// GetErrorList gets field.ErrorList type from underlying error. Errors are searched in the whole tree of wrapped
+// errors (including errors joined with errors.Join), so lists of all accumulated encode and decode errors are
+// concatenated.
 func GetErrorList(err error) field.ErrorList {
-	de := &decodeError{}
-	if errors.As(err, &de) {
-		return de.fieldErrors
+	var list field.ErrorList
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *fieldError:
+		return e.fieldErrors
+	case interface{ Unwrap() error }:
+		list = GetErrorList(e.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			list = append(list, GetErrorList(inner)...)
+		}
 	}
-	return nil
+	return list
 }
@@ -21,4 +21,8 @@ import "reflect"
 type structField struct {
 	value reflect.Value
 	tag   *parsedTag
+	// prefix is accumulated key prefix of fields contained in inline struct.
+	prefix string
+	// path is dot separated path of field names.
+	path string
 }
@@ -0,0 +1,69 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkFinalizers verifies if field tagged with 'finalizers' is a slice of string kind.
+func checkFinalizers(t reflect.Type, pt *parsedTag) error {
+	if pt.source != finalizers {
+		return nil
+	}
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.String {
+		return fmt.Errorf("'finalizers' requires slice of string kind, got '%s'", t)
+	}
+	return nil
+}
+
+// decodeFinalizers copies list into out. Empty list sets nil slice.
+func decodeFinalizers(out reflect.Value, list []string) error {
+	if out.Kind() != reflect.Slice || out.Type().Elem().Kind() != reflect.String {
+		return fmt.Errorf("finalizers require slice of string kind, got '%s'", out.Type())
+	}
+	if len(list) == 0 {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+	s := reflect.MakeSlice(out.Type(), len(list), len(list))
+	for i, f := range list {
+		s.Index(i).SetString(f)
+	}
+	out.Set(s)
+	return nil
+}
+
+// encodeFinalizers replaces finalizers of meta with copy of in. Empty slice removes all finalizers.
+func encodeFinalizers(in reflect.Value, meta metav1.Object) error {
+	if in.Kind() != reflect.Slice || in.Type().Elem().Kind() != reflect.String {
+		return fmt.Errorf("finalizers require slice of string kind, got '%s'", in.Type())
+	}
+	if in.Len() == 0 {
+		meta.SetFinalizers(nil)
+		return nil
+	}
+	list := make([]string, in.Len())
+	for i := range list {
+		list[i] = in.Index(i).String()
+	}
+	meta.SetFinalizers(list)
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fuzzStruct struct {
+	S   string         `k8s:"annotation:s"`
+	I   int64          `k8s:"annotation:i"`
+	U   uint8          `k8s:"label:u"`
+	F32 float32        `k8s:"annotation:f32"`
+	F64 float64        `k8s:"annotation:f64"`
+	B   bool           `k8s:"label:b"`
+	L   []string       `k8s:"annotation:l"`
+	M   map[string]int `k8s:"annotation:m"`
+	P   *int16         `k8s:"annotation:p"`
+}
+
+// FuzzRoundTrip checks if values encoded into metadata are decoded back unchanged.
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("value", int64(-1), uint8(7), float32(0.1), 1e-7, true, "a|b,c|d:e", int16(3))
+	f.Add("", int64(math.MaxInt64), uint8(0), float32(math.MaxFloat32), math.SmallestNonzeroFloat64, false, "", int16(0))
+	f.Add("a,b", int64(0), uint8(255), float32(-3.4e-38), -123456.789, true, "|", int16(-1))
+	f.Fuzz(func(t *testing.T, s string, i int64, u uint8, f32 float32, f64 float64, b bool, elems string, p int16) {
+		if math.IsNaN(float64(f32)) || math.IsNaN(f64) {
+			t.Skip("NaN is not equal to itself")
+		}
+		if !utf8.ValidString(elems) {
+			t.Skip("metadata transferred as JSON cannot hold invalid UTF-8")
+		}
+		in := fuzzStruct{S: s, I: i, U: u, F32: f32, F64: f64, B: b, P: &p}
+		if elems != "" || b {
+			in.L = strings.Split(elems, "|")
+			in.M = map[string]int{}
+			for j, e := range in.L {
+				in.M[e] = j
+			}
+		}
+		m := &metav1.ObjectMeta{}
+		if err := Marshal(&in, m, AutoJSONCollections()); err != nil {
+			t.Fatalf("unable to encode %+v: %v", in, err)
+		}
+		out := fuzzStruct{}
+		if err := Unmarshal(m, &out, DecodeAutoJSONCollections()); err != nil {
+			t.Fatalf("unable to decode %+v from %v: %v", in, m, err)
+		}
+		// nil and empty collections are encoded the same way
+		if len(in.L) == 0 && len(out.L) == 0 {
+			out.L = in.L
+		}
+		if len(in.M) == 0 && len(out.M) == 0 {
+			out.M = in.M
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Fatalf("round trip mismatch:\n in: %+v\nout: %+v\nmeta: %v", in, out, m)
+		}
+	})
+}
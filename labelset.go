@@ -0,0 +1,70 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// isStringMap checks if t is a map with keys and values of string kind (e.g. labels.Set).
+func isStringMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+}
+
+// decodeLabelSet parses in as equality-based label selector (e.g. "app=foo,tier=bar") and stores it in out.
+func decodeLabelSet(out reflect.Value, in string) error {
+	if out.Kind() == reflect.Pointer {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	if !isStringMap(out.Type()) {
+		return fmt.Errorf("labelset encoding requires map with string keys and values, got '%s'", out.Type())
+	}
+	set, err := labels.ConvertSelectorToLabelsMap(in)
+	if err != nil {
+		return fmt.Errorf("invalid label selector '%s': [%w]", in, err)
+	}
+	m := reflect.MakeMapWithSize(out.Type(), len(set))
+	for k, v := range set {
+		m.SetMapIndex(reflect.ValueOf(k).Convert(out.Type().Key()), reflect.ValueOf(v).Convert(out.Type().Elem()))
+	}
+	out.Set(m)
+	return nil
+}
+
+// encodeLabelSet formats in as label selector with keys in sorted order. Nil pointer is encoded as empty string.
+func encodeLabelSet(in reflect.Value) (string, error) {
+	if in.Kind() == reflect.Pointer {
+		if in.IsNil() {
+			return "", nil
+		}
+		in = in.Elem()
+	}
+	if !isStringMap(in.Type()) {
+		return "", fmt.Errorf("labelset encoding requires map with string keys and values, got '%s'", in.Type())
+	}
+	set := make(labels.Set, in.Len())
+	for it := in.MapRange(); it.Next(); {
+		set[it.Key().String()] = it.Value().String()
+	}
+	return set.String(), nil
+}
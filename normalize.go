@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// metaView is a view of metadata with annotations and labels read once, so accessors of metadata returning copies
+// are not called for every decoded field. Keys may be normalized (see newNormalizedMeta).
+type metaView struct {
+	metav1.Object
+	annotations map[string]string
+	labels      map[string]string
+}
+
+func (m *metaView) GetAnnotations() map[string]string {
+	return m.annotations
+}
+
+func (m *metaView) GetLabels() map[string]string {
+	return m.labels
+}
+
+// newMetaView returns view of meta with snapshot of its annotations and labels.
+func newMetaView(meta metav1.Object) *metaView {
+	return &metaView{Object: meta, annotations: meta.GetAnnotations(), labels: meta.GetLabels()}
+}
+
+// newNormalizedMeta returns view of meta with annotation and label keys transformed by normalize.
+// It returns an error when two different keys are normalized to the same key.
+func newNormalizedMeta(meta metav1.Object, normalize func(string) string) (*metaView, error) {
+	annotations, err := normalizeKeys(meta.GetAnnotations(), normalize, annotation)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := normalizeKeys(meta.GetLabels(), normalize, label)
+	if err != nil {
+		return nil, err
+	}
+	return &metaView{Object: meta, annotations: annotations, labels: labels}, nil
+}
+
+func normalizeKeys(values map[string]string, normalize func(string) string, src source) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(map[string]string, len(values))
+	origin := make(map[string]string, len(values))
+	for _, k := range keys {
+		n := normalize(k)
+		if prev, ok := origin[n]; ok {
+			return nil, fmt.Errorf("ambiguous %s keys '%s' and '%s' normalized to '%s'", src, prev, k, n)
+		}
+		origin[n] = k
+		out[n] = values[k]
+	}
+	return out, nil
+}
+
+// originalMeta returns metadata not wrapped in view.
+func originalMeta(meta metav1.Object) metav1.Object {
+	if m, ok := meta.(*metaView); ok {
+		return m.Object
+	}
+	return meta
+}
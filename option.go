@@ -16,6 +16,11 @@ limitations under the License.
 
 package metaser
 
+import (
+	"encoding/json"
+	"reflect"
+)
+
 const (
 	valueFieldIndex = 0
 	isSetFieldIndex = 1
@@ -37,6 +42,14 @@ func None[T any]() Option[T] {
 	return Option[T]{isSet: false}
 }
 
+// FromPtr constructs new Option with value pointed by p. None is returned for nil pointer.
+func FromPtr[T any](p *T) Option[T] {
+	if p == nil {
+		return None[T]()
+	}
+	return Some(*p)
+}
+
 // Get gets contained value. If the values was not set it panics.
 func (s *Option[T]) Get() T {
 	if s.isSet {
@@ -53,7 +66,55 @@ func (s *Option[T]) GetOrDefault(def T) T {
 	return def
 }
 
+// Unwrap returns contained value and true if the value was set. Otherwise zero value and false are returned.
+func (s *Option[T]) Unwrap() (T, bool) {
+	if s.isSet {
+		return s.value, true
+	}
+	var zero T
+	return zero, false
+}
+
 // IsSet validates if internal option value was set.
 func (s *Option[_]) IsSet() bool {
 	return s.isSet
 }
+
+// Equal checks if both options are unset or both are set to deeply equal values.
+// Value of unset option is ignored.
+func (s *Option[T]) Equal(other Option[T]) bool {
+	if !s.isSet || !other.isSet {
+		return s.isSet == other.isSet
+	}
+	return reflect.DeepEqual(s.value, other.value)
+}
+
+// Filter returns the option if it is set and its value satisfies predicate. Otherwise None is returned.
+func (s *Option[T]) Filter(predicate func(T) bool) Option[T] {
+	if s.isSet && predicate(s.value) {
+		return *s
+	}
+	return None[T]()
+}
+
+// MarshalJSON encodes value of the option or null when it is unset.
+func (s Option[T]) MarshalJSON() ([]byte, error) {
+	if !s.isSet {
+		return []byte("null"), nil
+	}
+	return json.Marshal(s.value)
+}
+
+// UnmarshalJSON sets the option to decoded value. Null leaves the option unset.
+func (s *Option[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = None[T]()
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*s = Some(value)
+	return nil
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package metaser
 
 import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("Option", func() {
@@ -46,4 +51,133 @@ var _ = Describe("Option", func() {
 			Expect(v.GetOrDefault(false)).To(BeFalse())
 		})
 	})
+	Context("Equal", func() {
+		It("should compare set options by value", func() {
+			v := Some(1)
+			Expect(v.Equal(Some(1))).To(BeTrue())
+			Expect(v.Equal(Some(2))).To(BeFalse())
+			Expect(v.Equal(None[int]())).To(BeFalse())
+		})
+		It("should ignore value of unset options", func() {
+			v := Option[[]int]{value: []int{1}}
+			Expect(v.Equal(None[[]int]())).To(BeTrue())
+			Expect(v.Equal(Some([]int{1}))).To(BeFalse())
+		})
+	})
+	Context("Filter", func() {
+		It("should keep values satisfying predicate", func() {
+			positive := func(v int) bool { return v > 0 }
+			v := Some(1)
+			Expect(v.Filter(positive)).To(Equal(Some(1)))
+			v = Some(-1)
+			Expect(v.Filter(positive)).To(Equal(None[int]()))
+			v = None[int]()
+			Expect(v.Filter(positive)).To(Equal(None[int]()))
+		})
+	})
+	Context("FromPtr", func() {
+		It("should construct None from nil pointer", func() {
+			Expect(FromPtr[int](nil)).To(Equal(None[int]()))
+		})
+		It("should construct Some from pointed value", func() {
+			v := "x"
+			o := FromPtr(&v)
+			Expect(o).To(Equal(Some("x")))
+			v = "y"
+			Expect(o.Get()).To(Equal("x"))
+		})
+	})
+	Context("Unwrap", func() {
+		It("should return value and true for set option", func() {
+			v := Some(5)
+			got, ok := v.Unwrap()
+			Expect(ok).To(BeTrue())
+			Expect(got).To(Equal(5))
+		})
+		It("should return zero value and false for unset option", func() {
+			v := None[string]()
+			got, ok := v.Unwrap()
+			Expect(ok).To(BeFalse())
+			Expect(got).To(BeEmpty())
+		})
+	})
+	Context("JSON", func() {
+		It("should serialize value or null", func() {
+			type J struct {
+				A Option[int]    `json:"a"`
+				B Option[string] `json:"b"`
+			}
+			data, err := json.Marshal(J{A: Some(1)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(MatchJSON(`{"a":1,"b":null}`))
+			out := J{B: Some("x")}
+			Expect(json.Unmarshal(data, &out)).To(Succeed())
+			Expect(out).To(Equal(J{A: Some(1)}))
+		})
+	})
+})
+
+type optionStruct struct {
+	A Option[int]    `k8s:"annotation:a"`
+	B Option[string] `k8s:"annotation:b"`
+	C Option[bool]   `k8s:"label:c"`
+	D *Option[int]   `k8s:"label:d"`
+}
+
+var optionMeta = &metav1.ObjectMeta{
+	Annotations: map[string]string{"a": "1", "b": "x"},
+	Labels:      map[string]string{"c": "true", "d": "4"},
+}
+
+func BenchmarkDecodeOption(b *testing.B) {
+	dec := NewDecoder()
+	s := optionStruct{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := dec.Decode(optionMeta, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var _ = Describe("Decoding Option fields", func() {
+	It("should set value and mark option as set", func() {
+		s := optionStruct{}
+		Expect(Unmarshal(optionMeta, &s)).To(Succeed())
+		Expect(s.A).To(Equal(Some(1)))
+		Expect(s.B).To(Equal(Some("x")))
+		Expect(s.C).To(Equal(Some(true)))
+		Expect(*s.D).To(Equal(Some(4)))
+	})
+	It("should leave option unset when decoding fails", func() {
+		s := optionStruct{}
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"a": "x"}}, &s)
+		Expect(err).To(HaveOccurred())
+		Expect(s.A.IsSet()).To(BeFalse())
+	})
+})
+
+var _ = Describe("Decoding Option reached through non-addressable value", func() {
+	It("should decode into copy set back to interface", func() {
+		var holder any = None[int]()
+		Expect(decodeUndefined(&decodeContext{}, reflect.ValueOf(&holder).Elem(), "1")).To(Succeed())
+		Expect(holder).To(Equal(Some(1)))
+	})
+	It("should decode option field held by interface", func() {
+		s := struct {
+			V any `k8s:"annotation:v"`
+		}{V: None[int]()}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"v": "2"}}, &s)).To(Succeed())
+		Expect(s.V).To(Equal(Some(2)))
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"v": "x"}}, &s)
+		Expect(err).To(HaveOccurred())
+		Expect(s.V).To(Equal(Some(2)))
+	})
+	It("should decode map values of Option type", func() {
+		s := struct {
+			M map[string]Option[int] `k8s:"annotation:m"`
+		}{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"m": "a:1"}}, &s)).To(Succeed())
+		Expect(s.M).To(Equal(map[string]Option[int]{"a": Some(1)}))
+	})
 })
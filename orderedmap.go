@@ -0,0 +1,110 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// orderedMapFields returns indexes of 'Key' and 'Value' fields of struct t.
+func orderedMapFields(t reflect.Type) (key, value int, err error) {
+	if t.Kind() == reflect.Struct {
+		k, kok := t.FieldByName("Key")
+		v, vok := t.FieldByName("Value")
+		if kok && vok && len(k.Index) == 1 && len(v.Index) == 1 && k.IsExported() && v.IsExported() {
+			return k.Index[0], v.Index[0], nil
+		}
+	}
+	return 0, 0, fmt.Errorf("orderedmap encoding requires slice of struct with 'Key' and 'Value' fields, got '%s'", t)
+}
+
+// decodeOrderedMap decodes comma separated list of <key>:<value> pairs into slice of structs with 'Key' and 'Value'
+// fields. Order of pairs is preserved.
+func decodeOrderedMap(dc *decodeContext, out reflect.Value, in string) error {
+	if out.Kind() == reflect.Pointer {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	if out.Kind() != reflect.Slice {
+		return fmt.Errorf("orderedmap encoding requires slice, got '%s'", out.Type())
+	}
+	keyIndex, valueIndex, err := orderedMapFields(out.Type().Elem())
+	if err != nil {
+		return err
+	}
+	if in == "" {
+		out.Set(reflect.MakeSlice(out.Type(), 0, 0))
+		return nil
+	}
+	items := strings.Split(in, itemSeparator)
+	slice := reflect.MakeSlice(out.Type(), len(items), len(items))
+	for i, item := range items {
+		k, v, ok := strings.Cut(item, keyValueSeparator)
+		if !ok {
+			return fmt.Errorf("invalid map item syntax, expected <key>:<value>, got: %s", item)
+		}
+		if err = decodeUndefined(dc, slice.Index(i).Field(keyIndex), k); err != nil {
+			return fmt.Errorf("unable to decode map key '%s': [%w]", k, err)
+		}
+		if err = decodeUndefined(dc, slice.Index(i).Field(valueIndex), v); err != nil {
+			return fmt.Errorf("unable to decode map item (key '%s', value: '%s'): [%w]", k, v, err)
+		}
+	}
+	out.Set(slice)
+	return nil
+}
+
+// encodeOrderedMap encodes slice of structs with 'Key' and 'Value' fields as comma separated list of <key>:<value>
+// pairs in slice order.
+func encodeOrderedMap(ec *encodeContext, in reflect.Value) (string, error) {
+	if in.Kind() == reflect.Pointer {
+		if in.IsNil() {
+			return "", nil
+		}
+		in = in.Elem()
+	}
+	if in.Kind() != reflect.Slice {
+		return "", fmt.Errorf("orderedmap encoding requires slice, got '%s'", in.Type())
+	}
+	keyIndex, valueIndex, err := orderedMapFields(in.Type().Elem())
+	if err != nil {
+		return "", err
+	}
+	elems := make([]string, in.Len())
+	for i := range elems {
+		k, err := encodeUndefined(ec, in.Index(i).Field(keyIndex))
+		if err != nil {
+			return "", fmt.Errorf("cannot encode map key element: [%w]", err)
+		}
+		if strings.ContainsAny(k, itemSeparator+keyValueSeparator) {
+			return "", fmt.Errorf("map key '%s' cannot contain '%s' or '%s'", k, itemSeparator, keyValueSeparator)
+		}
+		v, err := encodeUndefined(ec, in.Index(i).Field(valueIndex))
+		if err != nil {
+			return "", fmt.Errorf("cannot encode map value element: [%w]", err)
+		}
+		if strings.Contains(v, itemSeparator) {
+			return "", fmt.Errorf("map value '%s' cannot contain '%s'", v, itemSeparator)
+		}
+		elems[i] = k + keyValueSeparator + v
+	}
+	return strings.Join(elems, itemSeparator), nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Fields with orderedmap encoding", func() {
+	type KV struct {
+		Key   string
+		Value string
+	}
+	type Port struct {
+		Key   string
+		Value int
+	}
+	type S struct {
+		Env   []KV    `k8s:"annotation:env,enc:orderedmap"`
+		Ports *[]Port `k8s:"annotation:ports,enc:orderedmap,omitempty"`
+	}
+
+	It("should decode pairs in order", func() {
+		s := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"env": "z:1,a:2,m:3", "ports": "http:80,admin:9000"}}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s.Env).To(Equal([]KV{{"z", "1"}, {"a", "2"}, {"m", "3"}}))
+		Expect(*s.Ports).To(Equal([]Port{{"http", 80}, {"admin", 9000}}))
+	})
+	It("should round-trip preserving order", func() {
+		ports := []Port{{"https", 443}, {"http", 80}}
+		in := S{Env: []KV{{"b", "x:y"}, {"a", ""}}, Ports: &ports}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"env": "b:x:y,a:", "ports": "https:443,http:80"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should decode empty value into empty slice", func() {
+		s := S{Env: []KV{{"a", "b"}}}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"env": ""}}, &s)).To(Succeed())
+		Expect(s.Env).To(BeEmpty())
+	})
+	It("should return an error for invalid item syntax", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"env": "a:1,b"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("invalid map item syntax, expected <key>:<value>, got: b")))
+	})
+	It("should return an error for key containing separator", func() {
+		err := Marshal(&S{Env: []KV{{"a:b", "1"}}}, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("map key 'a:b' cannot contain")))
+	})
+	It("should return an error for unsupported element type", func() {
+		err := Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"s": "a:b"}}, &struct {
+			S []string `k8s:"annotation:s,enc:orderedmap"`
+		}{})
+		Expect(err).To(MatchError(ContainSubstring("orderedmap encoding requires slice of struct with 'Key' and 'Value' fields")))
+	})
+})
@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var ownerReferenceType = reflect.TypeOf(metav1.OwnerReference{})
+
+// filterOwners returns owner references matching kind. Empty kind matches all references.
+// If matching is false, references not matching kind are returned.
+func filterOwners(refs []metav1.OwnerReference, kind string, matching bool) []metav1.OwnerReference {
+	var out []metav1.OwnerReference
+	for _, ref := range refs {
+		if (kind == "" || ref.Kind == kind) == matching {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+// assignOwner stores owner reference in out. If out is not metav1.OwnerReference, the value is
+// converted through its json representation.
+func assignOwner(out reflect.Value, ref metav1.OwnerReference) error {
+	if out.Type() == ownerReferenceType {
+		out.Set(reflect.ValueOf(ref))
+		return nil
+	}
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return fmt.Errorf("cannot marshal owner reference: [%w]", err)
+	}
+	v := reflect.New(out.Type())
+	if err = json.Unmarshal(data, v.Interface()); err != nil {
+		return fmt.Errorf("cannot unmarshal owner reference: [%w]", err)
+	}
+	out.Set(v.Elem())
+	return nil
+}
+
+// ownerOf converts in into owner reference. If in is not metav1.OwnerReference, the value is
+// converted through its json representation.
+func ownerOf(in reflect.Value) (metav1.OwnerReference, error) {
+	ref := metav1.OwnerReference{}
+	if in.Type() == ownerReferenceType {
+		return in.Interface().(metav1.OwnerReference), nil
+	}
+	data, err := json.Marshal(in.Interface())
+	if err != nil {
+		return ref, fmt.Errorf("cannot marshal owner reference: [%w]", err)
+	}
+	if err = json.Unmarshal(data, &ref); err != nil {
+		return ref, fmt.Errorf("cannot unmarshal owner reference: [%w]", err)
+	}
+	return ref, nil
+}
+
+// decodeOwner stores owner references matching kind in out. Slices receive all matching references,
+// other types receive the first one.
+func decodeOwner(out reflect.Value, refs []metav1.OwnerReference, kind string) error {
+	if out.Kind() == reflect.Pointer {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		out = out.Elem()
+	}
+	refs = filterOwners(refs, kind, true)
+	if out.Kind() == reflect.Slice {
+		if len(refs) == 0 {
+			out.Set(reflect.Zero(out.Type()))
+			return nil
+		}
+		slice := reflect.MakeSlice(out.Type(), len(refs), len(refs))
+		for i, ref := range refs {
+			if err := assignOwner(slice.Index(i), ref); err != nil {
+				return fmt.Errorf("unable to decode owner reference at index %d: [%w]", i, err)
+			}
+		}
+		out.Set(slice)
+		return nil
+	}
+	if len(refs) == 0 {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+	return assignOwner(out, refs[0])
+}
+
+// encodeOwner writes owner references from in into meta. Slices replace all references matching kind.
+// Other types replace reference with the same UID (or the same kind if kind is set) or are appended.
+func encodeOwner(in reflect.Value, meta metav1.Object, kind string) error {
+	if in.Kind() == reflect.Pointer {
+		if in.IsNil() {
+			return nil
+		}
+		in = in.Elem()
+	}
+	refs := meta.GetOwnerReferences()
+	if in.Kind() == reflect.Slice {
+		refs = filterOwners(refs, kind, false)
+		for i := 0; i < in.Len(); i++ {
+			ref, err := ownerOf(in.Index(i))
+			if err != nil {
+				return fmt.Errorf("unable to encode owner reference at index %d: [%w]", i, err)
+			}
+			refs = append(refs, ref)
+		}
+		meta.SetOwnerReferences(refs)
+		return nil
+	}
+	ref, err := ownerOf(in)
+	if err != nil {
+		return err
+	}
+	out := make([]metav1.OwnerReference, 0, len(refs)+1)
+	for _, r := range refs {
+		if (kind != "" && r.Kind == kind) || (kind == "" && r.UID == ref.UID) {
+			continue
+		}
+		out = append(out, r)
+	}
+	meta.SetOwnerReferences(append(out, ref))
+	return nil
+}
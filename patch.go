@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DecodeToPatch reads data from K8s object metadata and returns JSON merge patch (RFC 7386) describing which fields
+// of v differ from the metadata. Both values are serialized with encoding/json, so keys of the patch follow json tags
+// and the patch can be applied to JSON representation of v. metaser.Option fields are serialized as their values or
+// null when unset. v is not modified.
+func (dec *Decoder) DecodeToPatch(meta metav1.Object, v any, options ...DecodeOption) ([]byte, error) {
+	root := reflect.ValueOf(v)
+
+	if root.Kind() != reflect.Pointer || root.IsNil() {
+		return nil, fmt.Errorf("required pointer to value")
+	}
+
+	cp := reflect.New(root.Type().Elem())
+	deepCopy(cp.Elem(), root.Elem())
+
+	if err := dec.Decode(meta, cp.Interface(), options...); err != nil {
+		return nil, err
+	}
+
+	original, err := json.Marshal(root.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal original value: [%w]", err)
+	}
+	modified, err := json.Marshal(cp.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal decoded value: [%w]", err)
+	}
+	return createMergePatch(original, modified)
+}
+
+func createMergePatch(original, modified []byte) ([]byte, error) {
+	var o, m any
+	if err := json.Unmarshal(original, &o); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(modified, &m); err != nil {
+		return nil, err
+	}
+	om, ok1 := o.(map[string]any)
+	mm, ok2 := m.(map[string]any)
+	if !ok1 || !ok2 {
+		return modified, nil
+	}
+	return json.Marshal(diffObjects(om, mm))
+}
+
+// diffObjects returns merge patch object transforming original into modified.
+func diffObjects(original, modified map[string]any) map[string]any {
+	patch := map[string]any{}
+	for k, mv := range modified {
+		ov, ok := original[k]
+		if !ok {
+			patch[k] = mv
+			continue
+		}
+		if reflect.DeepEqual(ov, mv) {
+			continue
+		}
+		om, ok1 := ov.(map[string]any)
+		mm, ok2 := mv.(map[string]any)
+		if ok1 && ok2 {
+			patch[k] = diffObjects(om, mm)
+			continue
+		}
+		patch[k] = mv
+	}
+	for k := range original {
+		if _, ok := modified[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	return patch
+}
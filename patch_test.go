@@ -0,0 +1,93 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("DecodeToPatch", func() {
+	type Inner struct {
+		C int      `json:"c" k8s:"annotation:c"`
+		D []string `json:"d" k8s:"annotation:d"`
+	}
+	type S struct {
+		A     string `json:"a" k8s:"annotation:a"`
+		B     *int   `json:"b" k8s:"label:b"`
+		Inner Inner  `json:"inner" k8s:"inline"`
+	}
+	It("should return patch containing only changed fields", func() {
+		b := 1
+		s := S{A: "same", B: &b, Inner: Inner{C: 1, D: []string{"x"}}}
+		m := &metav1.ObjectMeta{
+			Annotations: map[string]string{"a": "same", "c": "2", "d": "x"},
+			Labels:      map[string]string{"b": "3"},
+		}
+		patch, err := NewDecoder().DecodeToPatch(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patch).To(MatchJSON(`{"b":3,"inner":{"c":2}}`))
+		Expect(s).To(Equal(S{A: "same", B: &b, Inner: Inner{C: 1, D: []string{"x"}}}))
+		Expect(b).To(Equal(1))
+	})
+	It("should include changed Option fields", func() {
+		type O struct {
+			A Option[int] `json:"a" k8s:"annotation:a"`
+			B Option[int] `json:"b" k8s:"annotation:b"`
+			C string      `json:"c" k8s:"annotation:c"`
+		}
+		s := O{A: Some(1), B: Some(2), C: "x"}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "1", "b": "5", "c": "y"}}
+		patch, err := NewDecoder().DecodeToPatch(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patch).To(MatchJSON(`{"b":5,"c":"y"}`))
+	})
+	It("should include Option set by decoding", func() {
+		type O struct {
+			A Option[int] `k8s:"annotation:a"`
+			B Option[int] `k8s:"annotation:b"`
+		}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "3"}}
+		patch, err := NewDecoder().DecodeToPatch(m, &O{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patch).To(MatchJSON(`{"A":3}`))
+	})
+	It("should follow json tags of fields", func() {
+		type T struct {
+			A string      `json:"a,omitempty" k8s:"annotation:a"`
+			B string      `json:"-" k8s:"annotation:b"`
+			C Option[int] `json:"c,omitempty" k8s:"annotation:c"`
+			D string      `json:"d,omitempty" k8s:"annotation:d"`
+		}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "z", "b": "y", "c": "1", "d": ""}}
+		patch, err := NewDecoder().DecodeToPatch(m, &T{D: "x"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patch).To(MatchJSON(`{"a":"z","c":1,"d":null}`))
+	})
+	It("should return empty patch when nothing changes", func() {
+		s := S{A: "same", Inner: Inner{D: []string{"x"}}}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"a": "same", "d": "x"}}
+		patch, err := NewDecoder().DecodeToPatch(m, &s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patch).To(MatchJSON(`{}`))
+	})
+	It("should return error when value is not a pointer", func() {
+		_, err := NewDecoder().DecodeToPatch(&metav1.ObjectMeta{}, S{})
+		Expect(err).To(HaveOccurred())
+	})
+})
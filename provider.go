@@ -0,0 +1,105 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SourceProvider is a backend of custom source referenced with 'src:<provider>=<key>' tag. It allows to
+// read and write fields from places other than object metadata (e.g. object spec or external store).
+type SourceProvider interface {
+	// Get returns value stored under key and true, or false if the key does not exist.
+	Get(key string) (string, bool, error)
+	// Set stores value under key.
+	Set(key, value string) error
+	// Delete removes key.
+	Delete(key string) error
+}
+
+// DecodeSourceProvider registers provider used to decode fields tagged with 'src:<name>=<key>'.
+func DecodeSourceProvider(name string, provider SourceProvider) DecodeOption {
+	return func(dec *decodeContext) {
+		if dec.providers == nil {
+			dec.providers = map[string]SourceProvider{}
+		}
+		dec.providers[name] = provider
+	}
+}
+
+// EncodeSourceProvider registers provider used to encode fields tagged with 'src:<name>=<key>'.
+func EncodeSourceProvider(name string, provider SourceProvider) EncodeOption {
+	return func(enc *encodeContext) {
+		if enc.providers == nil {
+			enc.providers = map[string]SourceProvider{}
+		}
+		enc.providers[name] = provider
+	}
+}
+
+func lookupProvider(providers map[string]SourceProvider, tag *parsedTag) (SourceProvider, error) {
+	p, ok := providers[tag.provider]
+	if !ok {
+		return nil, fmt.Errorf("source provider '%s' is not registered", tag.provider)
+	}
+	return p, nil
+}
+
+// decodeProvided decodes field from value returned by provider. Absent key leaves field intact.
+func decodeProvided(dc *decodeContext, out reflect.Value, tag *parsedTag) error {
+	p, err := lookupProvider(dc.providers, tag)
+	if err != nil {
+		return err
+	}
+	val, ok, err := p.Get(tag.value)
+	if err != nil {
+		return fmt.Errorf("cannot get value from source provider '%s': [%w]", tag.provider, err)
+	}
+	if !ok {
+		return nil
+	}
+	return decodeWithEncoder(dc, out, val, tag)
+}
+
+// encodeProvided encodes field and stores it using provider.
+func encodeProvided(ec *encodeContext, dv *structField) error {
+	p, err := lookupProvider(ec.providers, dv.tag)
+	if err != nil {
+		return err
+	}
+	val, err := encode(ec, dv.value, dv.tag)
+	if err != nil {
+		return err
+	}
+	if err = p.Set(dv.tag.value, val); err != nil {
+		return fmt.Errorf("cannot set value in source provider '%s': [%w]", dv.tag.provider, err)
+	}
+	return nil
+}
+
+// deleteProvided removes key of field using provider.
+func deleteProvided(ec *encodeContext, tag *parsedTag) error {
+	p, err := lookupProvider(ec.providers, tag)
+	if err != nil {
+		return err
+	}
+	if err = p.Delete(tag.value); err != nil {
+		return fmt.Errorf("cannot delete value in source provider '%s': [%w]", tag.provider, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type memoryProvider map[string]string
+
+func (m memoryProvider) Get(key string) (string, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+func (m memoryProvider) Set(key, value string) error {
+	if key == "readonly" {
+		return errors.New("read only key")
+	}
+	m[key] = value
+	return nil
+}
+
+func (m memoryProvider) Delete(key string) error {
+	delete(m, key)
+	return nil
+}
+
+var _ = Describe("Source providers", func() {
+	type S struct {
+		Replicas int               `k8s:"src:spec=replicas"`
+		Tags     map[string]string `k8s:"src:spec=tags,enc:json,omitempty"`
+		Note     string            `k8s:"annotation:note"`
+	}
+
+	It("should decode fields from provider", func() {
+		store := memoryProvider{"replicas": "3", "tags": `{"a":"b"}`}
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Annotations: map[string]string{"note": "n"}}, &s,
+			DecodeSourceProvider("spec", store))).To(Succeed())
+		Expect(s).To(Equal(S{Replicas: 3, Tags: map[string]string{"a": "b"}, Note: "n"}))
+	})
+	It("should leave field intact when key is absent in provider", func() {
+		s := S{Replicas: 7}
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &s, DecodeSourceProvider("spec", memoryProvider{}))).To(Succeed())
+		Expect(s.Replicas).To(Equal(7))
+	})
+	It("should encode fields into provider", func() {
+		store := memoryProvider{"tags": "{}"}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Replicas: 5, Note: "n"}, m, EncodeSourceProvider("spec", store))).To(Succeed())
+		Expect(store).To(Equal(memoryProvider{"replicas": "5"}))
+		Expect(m.Annotations).To(Equal(map[string]string{"note": "n"}))
+	})
+	It("should return error when provider is not registered", func() {
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &S{})).To(MatchError(ContainSubstring("source provider 'spec' is not registered")))
+		Expect(Marshal(&S{}, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+	It("should return error when provider fails", func() {
+		s := struct {
+			V string `k8s:"src:spec=readonly"`
+		}{V: "x"}
+		Expect(Marshal(&s, &metav1.ObjectMeta{}, EncodeSourceProvider("spec", memoryProvider{}))).To(MatchError(ContainSubstring("read only key")))
+	})
+	It("should return error for invalid src tag", func() {
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &struct {
+			V string `k8s:"src:spec"`
+		}{})).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,193 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const (
+	rangeMinSuffix = "-min"
+	rangeMaxSuffix = "-max"
+	minFieldIndex  = 0
+	maxFieldIndex  = 1
+)
+
+// Range is a type representing range of values. It is serialized into two annotations or labels: '<key>-min'
+// and '<key>-max'. Min must not be greater than Max during decoding and encoding. Range can be held by pointer
+// or Option, then nil pointer or unset Option means that both keys are absent.
+type Range[T cmp.Ordered] struct {
+	Min T
+	Max T
+}
+
+// rangeMarker is implemented by every instantiation of Range, so it is recognised regardless of type argument.
+type rangeMarker interface {
+	isRange()
+}
+
+func (Range[T]) isRange() {}
+
+var rangeMarkerType = reflect.TypeOf((*rangeMarker)(nil)).Elem()
+
+// isRangeType checks if t is a Range, pointer to Range or Option of Range.
+func isRangeType(t reflect.Type) bool {
+	return valueType(t).Implements(rangeMarkerType)
+}
+
+// rangeValue returns Range held by v after dereferencing pointers and Options. It returns false for nil pointer
+// or unset Option.
+func rangeValue(v reflect.Value) (reflect.Value, bool) {
+	for {
+		switch {
+		case v.Kind() == reflect.Pointer:
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		case isOption(v):
+			if !v.Field(isSetFieldIndex).Bool() {
+				return v, false
+			}
+			v = optionValue(v)
+		default:
+			return v, true
+		}
+	}
+}
+
+// setRange stores range r into out, allocating nil pointers and marking Options as set. Existing pointee is
+// overwritten in place.
+func setRange(out, r reflect.Value) {
+	for {
+		switch {
+		case out.Kind() == reflect.Pointer:
+			if out.IsNil() {
+				out.Set(reflect.New(out.Type().Elem()))
+			}
+			out = out.Elem()
+		case isOption(out):
+			asWritableValue(out.Field(isSetFieldIndex)).SetBool(true)
+			out = asWritableValue(out.Field(valueFieldIndex))
+		default:
+			out.Set(r)
+			return
+		}
+	}
+}
+
+// markRange sets isRange flag of tag of field with type t if t is a Range. It returns an error if tag cannot be
+// used with range.
+func markRange(t reflect.Type, pt *parsedTag) error {
+	if !isRangeType(t) {
+		return nil
+	}
+	if (pt.source != annotation && pt.source != label) || pt.enc != encoder(undefined) {
+		return errors.New("range can be used only with 'annotation' or 'label' without encoding")
+	}
+	if len(pt.aliases) > 0 || len(pt.fallbacks) > 0 {
+		return errors.New("range cannot be used with aliases or alternative sources")
+	}
+	pt.isRange = true
+	return nil
+}
+
+// rangeKeys returns keys of min and max values of range stored under key.
+func rangeKeys(key string) (string, string) {
+	return key + rangeMinSuffix, key + rangeMaxSuffix
+}
+
+// checkRange returns an error when Min of range v is greater than Max.
+func checkRange(v reflect.Value) error {
+	lo, hi := v.Field(minFieldIndex), v.Field(maxFieldIndex)
+	var greater bool
+	switch lo.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		greater = lo.Int() > hi.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		greater = lo.Uint() > hi.Uint()
+	case reflect.Float32, reflect.Float64:
+		greater = lo.Float() > hi.Float()
+	case reflect.String:
+		greater = lo.String() > hi.String()
+	}
+	if greater {
+		return fmt.Errorf("invalid range: min '%v' is greater than max '%v'", lo, hi)
+	}
+	return nil
+}
+
+// decodeRange decodes min and max values of range present in metadata and validates resulting range. Nil pointer
+// and unset Option are left intact if both keys are absent.
+func decodeRange(dc *decodeContext, out reflect.Value, tag *parsedTag) error {
+	values := sourceValues(dc.meta, tag.source)
+	minKey, maxKey := rangeKeys(tag.value)
+	lo, minOk := values[minKey]
+	hi, maxOk := values[maxKey]
+	cur, ok := rangeValue(out)
+	if !ok && !minOk && !maxOk {
+		return nil
+	}
+	r := reflect.New(valueType(out.Type())).Elem()
+	if ok {
+		r.Set(cur)
+	}
+	if minOk {
+		if err := decodePrimitive(dc, r.Field(minFieldIndex), lo); err != nil {
+			return fmt.Errorf("cannot decode '%s': [%w]", minKey, err)
+		}
+	}
+	if maxOk {
+		if err := decodePrimitive(dc, r.Field(maxFieldIndex), hi); err != nil {
+			return fmt.Errorf("cannot decode '%s': [%w]", maxKey, err)
+		}
+	}
+	if err := checkRange(r); err != nil {
+		return err
+	}
+	setRange(out, r)
+	return nil
+}
+
+// encodeRange validates range and writes its min and max values into out. Both keys are removed for nil pointer
+// and unset Option.
+func encodeRange(ec *encodeContext, in reflect.Value, out map[string]string, written map[string]struct{}, tag *parsedTag) error {
+	minKey, maxKey := rangeKeys(tag.value)
+	in, ok := rangeValue(in)
+	if !ok {
+		delete(out, minKey)
+		delete(out, maxKey)
+		return nil
+	}
+	if err := checkRange(in); err != nil {
+		return err
+	}
+	lo, err := encodePrimitive(ec, in.Field(minFieldIndex))
+	if err != nil {
+		return fmt.Errorf("cannot encode '%s': [%w]", minKey, err)
+	}
+	hi, err := encodePrimitive(ec, in.Field(maxFieldIndex))
+	if err != nil {
+		return fmt.Errorf("cannot encode '%s': [%w]", maxKey, err)
+	}
+	out[minKey], out[maxKey] = lo, hi
+	written[minKey], written[maxKey] = struct{}{}, struct{}{}
+	return nil
+}
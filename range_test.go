@@ -0,0 +1,110 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Range", func() {
+	type S struct {
+		Replicas Range[int]     `k8s:"annotation:example.com/replicas"`
+		CPU      Range[float64] `k8s:"label:cpu,omitempty"`
+	}
+
+	It("should round-trip range values", func() {
+		in := S{Replicas: Range[int]{Min: 1, Max: 5}, CPU: Range[float64]{Min: 0.5, Max: 2}}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"example.com/replicas-min": "1", "example.com/replicas-max": "5"}))
+		Expect(m.Labels).To(Equal(map[string]string{"cpu-min": "0.5", "cpu-max": "2"}))
+		out := S{}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should decode range with single bound present", func() {
+		out := S{Replicas: Range[int]{Min: 1, Max: 3}}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/replicas-max": "10"}}
+		Expect(Unmarshal(m, &out)).To(Succeed())
+		Expect(out.Replicas).To(Equal(Range[int]{Min: 1, Max: 10}))
+	})
+	It("should remove both keys of empty range with omitempty", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"cpu-min": "1", "cpu-max": "2"}}
+		Expect(Marshal(&S{}, m)).To(Succeed())
+		Expect(m.Labels).To(BeEmpty())
+	})
+	It("should return error for invalid range on decode", func() {
+		out := S{}
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"example.com/replicas-min": "7", "example.com/replicas-max": "2"}}
+		Expect(Unmarshal(m, &out)).To(MatchError(ContainSubstring("min '7' is greater than max '2'")))
+		Expect(out.Replicas).To(BeZero())
+	})
+	It("should return error for invalid range on encode", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Replicas: Range[int]{Min: 3, Max: 1}}, m)).To(HaveOccurred())
+		Expect(m.Annotations).To(BeEmpty())
+	})
+	Context("held by pointer or Option", func() {
+		type P struct {
+			Ptr *Range[int]        `k8s:"annotation:ptr"`
+			Opt Option[Range[int]] `k8s:"label:opt"`
+		}
+
+		It("should round-trip range values", func() {
+			in := P{Ptr: &Range[int]{Min: 1, Max: 2}, Opt: Some(Range[int]{Min: 3, Max: 4})}
+			m := &metav1.ObjectMeta{}
+			Expect(Marshal(&in, m)).To(Succeed())
+			Expect(m.Annotations).To(Equal(map[string]string{"ptr-min": "1", "ptr-max": "2"}))
+			Expect(m.Labels).To(Equal(map[string]string{"opt-min": "3", "opt-max": "4"}))
+			out := P{}
+			Expect(Unmarshal(m, &out)).To(Succeed())
+			Expect(out).To(Equal(in))
+		})
+		It("should leave nil pointer and unset Option when keys are absent", func() {
+			out := P{}
+			Expect(Unmarshal(&metav1.ObjectMeta{}, &out)).To(Succeed())
+			Expect(out.Ptr).To(BeNil())
+			Expect(out.Opt.IsSet()).To(BeFalse())
+		})
+		It("should remove keys of nil pointer and unset Option", func() {
+			m := &metav1.ObjectMeta{Annotations: map[string]string{"ptr-min": "1", "ptr-max": "2"},
+				Labels: map[string]string{"opt-min": "3", "opt-max": "4"}}
+			Expect(Marshal(&P{}, m)).To(Succeed())
+			Expect(m.Annotations).To(BeEmpty())
+			Expect(m.Labels).To(BeEmpty())
+		})
+		It("should validate range held by pointer", func() {
+			Expect(Marshal(&P{Ptr: &Range[int]{Min: 3, Max: 1}}, &metav1.ObjectMeta{})).To(HaveOccurred())
+			out := P{}
+			m := &metav1.ObjectMeta{Annotations: map[string]string{"ptr-min": "7", "ptr-max": "2"}}
+			Expect(Unmarshal(m, &out)).To(MatchError(ContainSubstring("min '7' is greater than max '2'")))
+			Expect(out.Ptr).To(BeNil())
+		})
+		It("should return error for pointer to range with aliases", func() {
+			Expect(Unmarshal(&metav1.ObjectMeta{}, &struct {
+				R *Range[int] `k8s:"annotation:r,aliases:x"`
+			}{})).To(HaveOccurred())
+		})
+	})
+	It("should return error for range with aliases", func() {
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &struct {
+			R Range[int] `k8s:"annotation:r,aliases:x"`
+		}{})).To(HaveOccurred())
+	})
+})
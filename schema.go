@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Schema describes metadata fields read and written by a type.
+type Schema struct {
+	// Type is the name of described type.
+	Type   string        `json:"type"`
+	Fields []FieldSchema `json:"fields"`
+}
+
+// FieldSchema describes single tagged field of a type.
+type FieldSchema struct {
+	// Path is a dot separated path of Go field names from the described type.
+	Path string `json:"path"`
+	// Source is one of name, namespace, annotation, label, owner or labelpresence. Empty for custom and inline fields.
+	Source string `json:"source,omitempty"`
+	// Key is annotation or label key (including inline prefixes), owner kind, path of custom field scoping keys of
+	// slice elements or empty.
+	Key     string   `json:"key,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Fallbacks are alternative sources in "<source>:<key>" form.
+	Fallbacks []string `json:"fallbacks,omitempty"`
+	Direction string   `json:"direction"`
+	// Encoding is value of 'enc' tag including its parameters (e.g. "base:16") or "default".
+	Encoding  string `json:"encoding"`
+	Inline    bool   `json:"inline,omitempty"`
+	OmitEmpty bool   `json:"omitEmpty,omitempty"`
+	Immutable bool   `json:"immutable,omitempty"`
+	SetOnce   bool   `json:"setOnce,omitempty"`
+	// Default is raw value decoded when the key is absent. Nil if field has no default value.
+	Default *string `json:"default,omitempty"`
+	// Template composes name from values of other fields.
+	Template string `json:"template,omitempty"`
+}
+
+// TagInfo describes parsed k8s struct tag.
+type TagInfo struct {
+	// Source is one of sources described in package documentation. Empty for custom and inline fields.
+	Source string `json:"source,omitempty"`
+	// Key is annotation or label key, owner kind, provider key or empty.
+	Key     string   `json:"key,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Fallbacks are alternative sources in "<source>:<key>" form.
+	Fallbacks []string `json:"fallbacks,omitempty"`
+	Direction string   `json:"direction"`
+	// Encoding is value of 'enc' tag including its parameters (e.g. "base:16") or "default".
+	Encoding string `json:"encoding"`
+	// Prefix is prepended to keys of fields contained in inline struct.
+	Prefix    string `json:"prefix,omitempty"`
+	Inline    bool   `json:"inline,omitempty"`
+	OmitEmpty bool   `json:"omitEmpty,omitempty"`
+	Immutable bool   `json:"immutable,omitempty"`
+	SetOnce   bool   `json:"setOnce,omitempty"`
+	// Default is raw value decoded when the key is absent. Nil if field has no default value.
+	Default *string `json:"default,omitempty"`
+	// Template composes name from values of other fields.
+	Template string `json:"template,omitempty"`
+}
+
+// ParseStructTag parses k8s key of struct tag with the same rules as Decoder and Encoder, so tools (e.g. linters)
+// can validate tags without reimplementing the syntax. It returns nil TagInfo if the tag has no k8s key.
+// Checks depending on type of the field are performed only by Decoder and Encoder.
+func ParseStructTag(tag reflect.StructTag) (*TagInfo, error) {
+	pt, err := parseTag(tag)
+	if err != nil || pt == nil {
+		return nil, err
+	}
+	info := describeTag(pt)
+	return &info, nil
+}
+
+// DescribeSchema returns description of all tagged fields of v, which should be a struct or a pointer to struct.
+func DescribeSchema(v any) (Schema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return Schema{}, fmt.Errorf("expected struct or pointer to struct, got '%v'", reflect.TypeOf(v))
+	}
+	c, err := newTypeCache(t, nil)
+	if err != nil {
+		return Schema{}, err
+	}
+	s := Schema{Type: t.String(), Fields: make([]FieldSchema, 0, len(c.Fields))}
+	for _, info := range c.Fields {
+		s.Fields = append(s.Fields, describeField(t, &info))
+	}
+	return s, nil
+}
+
+func describeField(t reflect.Type, info *fieldInfo) FieldSchema {
+	ti := describeTag(&info.tag)
+	return FieldSchema{
+		Path:      fieldName(t, info.path),
+		Source:    ti.Source,
+		Key:       ti.Key,
+		Aliases:   ti.Aliases,
+		Fallbacks: ti.Fallbacks,
+		Direction: ti.Direction,
+		Encoding:  ti.Encoding,
+		Inline:    ti.Inline,
+		OmitEmpty: ti.OmitEmpty,
+		Immutable: ti.Immutable,
+		SetOnce:   ti.SetOnce,
+		Default:   ti.Default,
+		Template:  ti.Template,
+	}
+}
+
+func describeTag(tag *parsedTag) TagInfo {
+	ti := TagInfo{
+		Key:       tag.value,
+		Direction: tag.dir.String(),
+		Encoding:  describeEncoding(tag),
+		Prefix:    tag.prefix,
+		Inline:    tag.inline,
+		OmitEmpty: tag.omitempty,
+		Immutable: tag.immutable,
+		SetOnce:   tag.setOnce,
+		Template:  tag.template,
+	}
+	if tag.hasDefault {
+		ti.Default = &tag.defaultValue
+	}
+	if len(tag.aliases) > 0 {
+		ti.Aliases = tag.aliases
+	}
+	if tag.source != source(undefined) {
+		ti.Source = tag.source.String()
+	}
+	for _, ref := range tag.fallbacks {
+		ti.Fallbacks = append(ti.Fallbacks, ref.source.String()+keyValueSeparator+ref.value)
+	}
+	return ti
+}
+
+// describeEncoding returns encoding of tag in the same form as value of 'enc' tag, including parameters.
+func describeEncoding(tag *parsedTag) string {
+	switch tag.enc {
+	case baseEnc:
+		return baseKey + keyValueSeparator + strconv.Itoa(tag.params.base)
+	case boolEnc:
+		return boolKey + keyValueSeparator + tag.params.trueToken + boolTokenSeparator + tag.params.falseToken
+	case jsonEnc:
+		if tag.params.indent {
+			return jsonKey + keyValueSeparator + indentKey
+		}
+	case percentSuffixEnc:
+		return percentIntKey + keyValueSeparator + suffixKey
+	}
+	return tag.enc.String()
+}
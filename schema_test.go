@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DescribeSchema", func() {
+	type Inner struct {
+		Replicas int `k8s:"annotation:replicas,aliases:size;count,immutable"`
+	}
+	type S struct {
+		Name   string            `k8s:"name"`
+		Inner  Inner             `k8s:"inline,prefix:app/"`
+		Tags   map[string]string `k8s:"label:tags,enc:json,omitempty,out"`
+		Mode   string            `k8s:"annotation:mode|label:mode,setonce"`
+		Ignore string
+	}
+
+	It("should describe all tagged fields", func() {
+		s, err := DescribeSchema(&S{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Type).To(Equal("metaser.S"))
+		Expect(s.Fields).To(Equal([]FieldSchema{
+			{Path: "Name", Source: "name", Direction: "inout", Encoding: "default"},
+			{Path: "Inner", Direction: "inout", Encoding: "default", Inline: true},
+			{Path: "Tags", Source: "label", Key: "tags", Direction: "out", Encoding: "json", OmitEmpty: true},
+			{Path: "Mode", Source: "annotation", Key: "mode", Fallbacks: []string{"label:mode"}, Direction: "inout",
+				Encoding: "default", SetOnce: true},
+			{Path: "Inner.Replicas", Source: "annotation", Key: "app/replicas", Aliases: []string{"app/size", "app/count"},
+				Direction: "inout", Encoding: "default", Immutable: true},
+		}))
+	})
+	It("should return error for non-struct types", func() {
+		_, err := DescribeSchema(3)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ParseStructTag", func() {
+	defaultValue, emptyDefault := "3", ""
+	DescribeTable("should describe tag",
+		func(tag string, expected *TagInfo) {
+			info, err := ParseStructTag(reflect.StructTag(tag))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(Equal(expected))
+		},
+		Entry("without k8s key", `json:"a"`, nil),
+		Entry("name", `k8s:"name"`, &TagInfo{Source: "name", Direction: "inout", Encoding: "default"}),
+		Entry("namespace", `k8s:"namespace,in"`, &TagInfo{Source: "namespace", Direction: "in", Encoding: "default"}),
+		Entry("annotation with aliases", `k8s:"annotation:a,aliases:b;c,immutable"`,
+			&TagInfo{Source: "annotation", Key: "a", Aliases: []string{"b", "c"}, Direction: "inout", Encoding: "default",
+				Immutable: true}),
+		Entry("label with encoding", `k8s:"label:l,enc:json,omitempty,out"`,
+			&TagInfo{Source: "label", Key: "l", Direction: "out", Encoding: "json", OmitEmpty: true}),
+		Entry("alternative sources", `k8s:"annotation:m|label:m,setonce"`,
+			&TagInfo{Source: "annotation", Key: "m", Fallbacks: []string{"label:m"}, Direction: "inout",
+				Encoding: "default", SetOnce: true}),
+		Entry("owner", `k8s:"owner:Deployment"`, &TagInfo{Source: "owner", Key: "Deployment", Direction: "inout",
+			Encoding: "default"}),
+		Entry("labelpresence", `k8s:"labelpresence:p"`, &TagInfo{Source: "labelpresence", Key: "p", Direction: "inout",
+			Encoding: "default"}),
+		Entry("inline with prefix", `k8s:"inline,prefix:app/"`, &TagInfo{Direction: "inout", Encoding: "default",
+			Prefix: "app/", Inline: true}),
+		Entry("custom", `k8s:"enc:custom"`, &TagInfo{Direction: "inout", Encoding: "custom"}),
+		Entry("base encoding", `k8s:"annotation:a,enc:base:16"`, &TagInfo{Source: "annotation", Key: "a",
+			Direction: "inout", Encoding: "base:16"}),
+		Entry("bool encoding", `k8s:"annotation:a,enc:bool:yes/no"`, &TagInfo{Source: "annotation", Key: "a",
+			Direction: "inout", Encoding: "bool:yes/no"}),
+		Entry("indented json encoding", `k8s:"annotation:a,enc:json:indent"`, &TagInfo{Source: "annotation", Key: "a",
+			Direction: "inout", Encoding: "json:indent"}),
+		Entry("percentint with suffix", `k8s:"annotation:a,percentint:suffix"`, &TagInfo{Source: "annotation",
+			Key: "a", Direction: "inout", Encoding: "percentint:suffix"}),
+		Entry("default", `k8s:"annotation:a,default:3"`, &TagInfo{Source: "annotation", Key: "a", Direction: "inout",
+			Encoding: "default", Default: &defaultValue}),
+		Entry("empty default", `k8s:"label:l,default:"`, &TagInfo{Source: "label", Key: "l", Direction: "inout",
+			Encoding: "default", Default: &emptyDefault}),
+		Entry("template", `k8s:"name,out,template:{A}-{B}"`, &TagInfo{Source: "name", Direction: "out",
+			Encoding: "default", Template: "{A}-{B}"}),
+	)
+	It("should return an error for invalid tag", func() {
+		_, err := ParseStructTag(`k8s:"annotation:a,enc:unknown"`)
+		Expect(err).To(MatchError(ContainSubstring("invalid encoding value")))
+	})
+})
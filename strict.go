@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Strict enforces decoder to validate struct tags of all struct fields reachable from decoded type (through nested
+// structs and pointers) when the type is used for the first time. Unlike lazy cache building, which stops on the
+// first invalid tag, all invalid tags and fields with more than one 'k8s' tag are reported as a combined error.
+func (dec *Decoder) Strict() *Decoder {
+	dec.strict = true
+	return dec
+}
+
+// checkTags returns combined error of all invalid k8s tags of struct fields reachable from t.
+func checkTags(t reflect.Type) error {
+	return errors.Join(walkTags(t, "", nil)...)
+}
+
+func walkTags(t reflect.Type, path string, ancestors []reflect.Type) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == objectMetaType || t == typeMetaType || slices.Contains(ancestors, t) {
+		return nil
+	}
+	ancestors = append(ancestors, t)
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Name
+		if path != "" {
+			name = path + "." + name
+		}
+		if n := countTags(f.Tag); n > 1 {
+			errs = append(errs, fmt.Errorf("field '%s': %d k8s tags defined, expected at most one", name, n))
+		}
+		if _, err := parseTag(f.Tag); err != nil {
+			errs = append(errs, fmt.Errorf("field '%s': %w", name, err))
+		}
+		errs = append(errs, walkTags(f.Type, name, ancestors)...)
+	}
+	return errs
+}
+
+// countTags returns number of k8s keys in struct tag.
+func countTags(tag reflect.StructTag) int {
+	n := 0
+	for _, f := range strings.Fields(string(tag)) {
+		if strings.HasPrefix(f, k8sKey+keyValueSeparator) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Strict decoder", func() {
+	type Deep struct {
+		A string `k8s:"annotation:a,enc:yaml"`
+	}
+	type Nested struct {
+		Deep *Deep
+		B    int `k8s:"label:b,bogus"`
+	}
+	type S struct {
+		Nested Nested
+		C      string `k8s:"annotation:c" k8s:"label:c"`
+	}
+
+	It("should report all invalid tags on first use", func() {
+		err := NewDecoder().Strict().Decode(&metav1.ObjectMeta{}, &S{})
+		Expect(err).To(MatchError(ContainSubstring("invalid struct tags")))
+		Expect(err).To(MatchError(ContainSubstring("field 'Nested.Deep.A'")))
+		Expect(err).To(MatchError(ContainSubstring("field 'Nested.B'")))
+		Expect(err).To(MatchError(ContainSubstring("field 'C': 2 k8s tags defined")))
+	})
+	It("should report invalid tags of nested struct not reached by lazy cache building", func() {
+		type Outer struct {
+			Nested struct {
+				Deep Deep
+			}
+		}
+		Expect(NewDecoder().Decode(&metav1.ObjectMeta{}, &Outer{})).To(Succeed())
+		err := NewDecoder().Strict().Decode(&metav1.ObjectMeta{}, &Outer{})
+		Expect(err).To(MatchError(ContainSubstring("field 'Nested.Deep.A'")))
+	})
+	It("should decode types with valid tags", func() {
+		type Valid struct {
+			A string `k8s:"annotation:a"`
+			metav1.ObjectMeta
+		}
+		dec := NewDecoder().Strict()
+		v := Valid{}
+		Expect(dec.Decode(&metav1.ObjectMeta{Annotations: map[string]string{"a": "x"}}, &v)).To(Succeed())
+		Expect(v.A).To(Equal("x"))
+		Expect(dec.Decode(&metav1.ObjectMeta{}, &v)).To(Succeed())
+	})
+})
@@ -0,0 +1,97 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// RegisterStringer registers values of 'stringer' encoding. Fields of type of registered values (or pointers or
+// options of it) tagged with 'enc:stringer' are decoded by matching metadata value against String() of every value.
+// Values of the same type can be registered with multiple calls. Values should be registered before the Decoder
+// is used.
+func (dec *Decoder) RegisterStringer(values ...fmt.Stringer) *Decoder {
+	if dec.stringers == nil {
+		dec.stringers = map[reflect.Type]map[string]reflect.Value{}
+	}
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		if dec.stringers[t] == nil {
+			dec.stringers[t] = map[string]reflect.Value{}
+		}
+		dec.stringers[t][v.String()] = reflect.ValueOf(v)
+	}
+	return dec
+}
+
+// decodeStringer sets value registered with RegisterStringer which String() equals in.
+func decodeStringer(dc *decodeContext, out reflect.Value, in string) error {
+	switch {
+	case out.Kind() == reflect.Pointer:
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		return decodeStringer(dc, out.Elem(), in)
+	case isOption(out):
+		if err := decodeStringer(dc, asWritableValue(out.Field(valueFieldIndex)), in); err != nil {
+			return err
+		}
+		asWritableValue(out.Field(isSetFieldIndex)).SetBool(true)
+		return nil
+	}
+	values, ok := dc.stringers[out.Type()]
+	if !ok {
+		return fmt.Errorf("no values of type '%s' registered with RegisterStringer", out.Type())
+	}
+	v, ok := values[in]
+	if !ok {
+		return fmt.Errorf("unknown value '%s' of type '%s'", in, out.Type())
+	}
+	out.Set(v)
+	return nil
+}
+
+// encodeStringer encodes value with String method. Nil pointer and unset Option are encoded as empty string.
+func encodeStringer(in reflect.Value) (string, error) {
+	for in.Kind() == reflect.Pointer {
+		if in.IsNil() {
+			return "", nil
+		}
+		in = in.Elem()
+	}
+	if isOption(in) {
+		if !in.Field(isSetFieldIndex).Bool() {
+			return "", nil
+		}
+		return encodeStringer(optionValue(in))
+	}
+	if !in.Type().Implements(stringerType) {
+		if !reflect.PointerTo(in.Type()).Implements(stringerType) {
+			return "", fmt.Errorf("stringer encoding requires type implementing fmt.Stringer, got '%s'", in.Type())
+		}
+		if !in.CanAddr() {
+			c := reflect.New(in.Type()).Elem()
+			c.Set(in)
+			in = c
+		}
+		in = in.Addr()
+	}
+	return in.Interface().(fmt.Stringer).String(), nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type phase int
+
+const (
+	phasePending phase = iota
+	phaseRunning
+	phaseDone
+)
+
+func (p phase) String() string {
+	switch p {
+	case phasePending:
+		return "Pending"
+	case phaseRunning:
+		return "Running"
+	case phaseDone:
+		return "Done"
+	}
+	return "Unknown"
+}
+
+var _ = Describe("Fields with stringer encoding", func() {
+	type S struct {
+		Phase    phase         `k8s:"annotation:phase,enc:stringer"`
+		Previous *phase        `k8s:"annotation:previous,enc:stringer"`
+		Next     Option[phase] `k8s:"label:next,enc:stringer"`
+		Raw      phase         `k8s:"annotation:raw"`
+	}
+	var dec *Decoder
+
+	BeforeEach(func() {
+		dec = NewDecoder().RegisterStringer(phasePending, phaseRunning, phaseDone)
+	})
+
+	It("should encode values by name and decode them back", func() {
+		prev := phasePending
+		in := S{Phase: phaseRunning, Previous: &prev, Next: Some(phaseDone), Raw: phaseDone}
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&in, m)).To(Succeed())
+		Expect(m.Annotations).To(Equal(map[string]string{"phase": "Running", "previous": "Pending", "raw": "2"}))
+		Expect(m.Labels).To(Equal(map[string]string{"next": "Done"}))
+		out := S{}
+		Expect(dec.Decode(m, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+	})
+	It("should return an error for unknown name", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"phase": "Failed"}}
+		Expect(dec.Decode(m, &S{})).To(MatchError(ContainSubstring("unknown value 'Failed' of type 'metaser.phase'")))
+	})
+	It("should return an error when values are not registered", func() {
+		m := &metav1.ObjectMeta{Annotations: map[string]string{"phase": "Running"}}
+		Expect(Unmarshal(m, &S{})).To(MatchError(ContainSubstring("no values of type 'metaser.phase' registered")))
+	})
+	It("should return an error for type not implementing fmt.Stringer", func() {
+		err := Marshal(&struct {
+			A int `k8s:"annotation:a,enc:stringer"`
+		}{}, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("stringer encoding requires type implementing fmt.Stringer")))
+	})
+})
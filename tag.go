@@ -20,9 +20,19 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+// keyRef references single key in annotations or labels.
+type keyRef struct {
+	source source
+	value  string
+}
+
 type parsedTag struct {
 	source    source
 	enc       encoder
@@ -33,18 +43,96 @@ type parsedTag struct {
 	immutable bool
 	aliases   []string
 	setOnce   bool
+	// aliasFirst makes aliases take precedence over the key during decoding.
+	aliasFirst bool
+	// absent is value of labelpresence field when label does not exist.
+	absent bool
+	// prefix is prepended to annotation and label keys of fields contained in inline struct.
+	prefix string
+	// fallbacks are keys used during decoding when key defined by source and value is absent.
+	fallbacks []keyRef
+	// params are parameters of encoding scheme.
+	params encParams
+	// provider is name of SourceProvider for 'src' source.
+	provider string
+	// isRange is set for fields of Range type stored under '<value>-min' and '<value>-max' keys.
+	isRange bool
+	// maxBytes limits length of encoded annotation or label value. Zero means no limit.
+	maxBytes int
+	// dnsCheck validates raw value as DNS-1123 label or subdomain. Nil if validation is not requested.
+	dnsCheck func(value string) []string
+	// fold marks string field which value is lowercased on decoding with CaseInsensitive option.
+	fold bool
+	// defaultValue is raw value of annotation or label decoded when the key is absent. Valid only if hasDefault is set.
+	defaultValue string
+	hasDefault   bool
+	// template composes name from values of other fields referenced as {<field path>} placeholders.
+	template string
+}
+
+func parseKeyRef(expr string) (keyRef, error) {
+	keyvals := strings.Split(expr, keyValueSeparator)
+	if len(keyvals) == 2 {
+		switch keyvals[0] {
+		case annotationKey:
+			return keyRef{annotation, keyvals[1]}, nil
+		case labelKey:
+			return keyRef{label, keyvals[1]}, nil
+		}
+	}
+	return keyRef{}, fmt.Errorf("invalid source syntax. Expected annotation:<key> or label:<key>, got '%s'", expr)
 }
 
-func parseEncoding(expr string) (encoder, error) {
+// encParams holds parameters of encoding scheme given after its name (e.g. 'enc:base:16').
+type encParams struct {
+	// base is numeric base used by 'base' encoding.
+	base int
+	// trueToken and falseToken represent bool values in 'bool' encoding.
+	trueToken, falseToken string
+	// indent enables indentation of 'json' encoding.
+	indent bool
+}
+
+func parseEncoding(expr string) (encoder, encParams, error) {
+	if param, ok := strings.CutPrefix(expr, baseKey+keyValueSeparator); ok {
+		base, err := strconv.Atoi(param)
+		if err != nil || base < 2 || base > 36 {
+			return encoder(undefined), encParams{}, fmt.Errorf("invalid base '%s'. Expected integer within [2, 36] range", param)
+		}
+		return encoder(baseEnc), encParams{base: base}, nil
+	}
+	if param, ok := strings.CutPrefix(expr, boolKey+keyValueSeparator); ok {
+		t, f, ok := strings.Cut(param, boolTokenSeparator)
+		if !ok || t == "" || f == "" || t == f || strings.Contains(f, boolTokenSeparator) {
+			return encoder(undefined), encParams{}, fmt.Errorf("invalid bool tokens '%s'. Expected <true>/<false> pair of different tokens", param)
+		}
+		return encoder(boolEnc), encParams{trueToken: t, falseToken: f}, nil
+	}
 	switch expr {
 	case jsonKey:
-		return encoder(jsonEnc), nil
+		return encoder(jsonEnc), encParams{}, nil
+	case jsonKey + keyValueSeparator + indentKey:
+		return encoder(jsonEnc), encParams{indent: true}, nil
 	case customKey:
-		return encoder(custom), nil
+		return encoder(custom), encParams{}, nil
+	case binaryKey:
+		return encoder(binaryEnc), encParams{}, nil
+	case hexKey:
+		return encoder(hexEnc), encParams{}, nil
+	case ttlKey:
+		return encoder(ttlEnc), encParams{}, nil
+	case humanIntKey:
+		return encoder(humanIntEnc), encParams{}, nil
+	case stringerKey:
+		return encoder(stringerEnc), encParams{}, nil
+	case labelSetKey:
+		return encoder(labelSetEnc), encParams{}, nil
+	case orderedMapKey:
+		return encoder(orderedMapEnc), encParams{}, nil
 	case "":
-		return encoder(undefined), nil
+		return encoder(undefined), encParams{}, nil
 	default:
-		return encoder(undefined), errors.New("unsupported type")
+		return encoder(undefined), encParams{}, fmt.Errorf("%w '%s'", ErrUnsupportedType, expr)
 	}
 }
 
@@ -61,6 +149,9 @@ func parseTag(tag reflect.StructTag) (pt *parsedTag, err error) {
 		immutable: false,
 	}
 
+	percent := encoder(undefined)
+	absentSet := false
+
 	k8sTag := ""
 	for _, f := range strings.Fields(string(tag)) {
 		if strings.HasPrefix(f, k8sKey) {
@@ -79,6 +170,20 @@ func parseTag(tag reflect.StructTag) (pt *parsedTag, err error) {
 			pt.source = name
 		case namespaceKey:
 			pt.source = namespace
+		case ownerKey:
+			pt.source = owner
+		case creationTimestampKey:
+			pt.source = creationTimestamp
+		case deletionTimestampKey:
+			pt.source = deletionTimestamp
+		case finalizersKey:
+			pt.source = finalizers
+		case generationKey:
+			pt.source = generation
+		case labelsKey:
+			pt.source = allLabels
+		case annotationsKey:
+			pt.source = allAnnotations
 		case inlineKey:
 			pt.inline = true
 		case inKey:
@@ -93,16 +198,45 @@ func parseTag(tag reflect.StructTag) (pt *parsedTag, err error) {
 			pt.immutable = true
 		case setOnceKey:
 			pt.setOnce = true
+		case percentIntKey:
+			percent = percentEnc
+		case dns1123LabelKey:
+			pt.dnsCheck = validation.IsDNS1123Label
+		case dns1123SubdomainKey:
+			pt.dnsCheck = validation.IsDNS1123Subdomain
+		case foldKey:
+			pt.fold = true
+		case aliasFirstKey:
+			pt.aliasFirst = true
 		default:
+			// handle alternative sources separated by '|'
+			if strings.Contains(f, sourceSeparator) {
+				for i, expr := range strings.Split(f, sourceSeparator) {
+					ref, err := parseKeyRef(expr)
+					if err != nil {
+						return nil, err
+					}
+					if i == 0 {
+						pt.source, pt.value = ref.source, ref.value
+					} else {
+						pt.fallbacks = append(pt.fallbacks, ref)
+					}
+				}
+				continue
+			}
 			// handle key:value pairs
 			keyvals := strings.Split(f, ":")
+			if len(keyvals) == 3 && keyvals[0] == encodingKey {
+				// encoding with parameter, e.g. 'enc:base:16'
+				keyvals = []string{keyvals[0], keyvals[1] + keyValueSeparator + keyvals[2]}
+			}
 			if len(keyvals) != 2 {
 				return nil, fmt.Errorf("invalid encoding tag syntax. Unknown k8s option: '%s'", f)
 			}
 			switch keyvals[0] {
 			case encodingKey:
-				if pt.enc, err = parseEncoding(keyvals[1]); err != nil {
-					return nil, fmt.Errorf("invalid encoding value. Expected one of [json], got '%s': [%w]", keyvals[1], err)
+				if pt.enc, pt.params, err = parseEncoding(keyvals[1]); err != nil {
+					return nil, fmt.Errorf("invalid encoding value. Expected one of [json, json:indent, custom, binary, hex, ttl, humanint, stringer, labelset, orderedmap, base:<n>, bool:<true>/<false>], got '%s': [%w]", keyvals[1], err)
 				}
 			case annotationKey:
 				pt.source = annotation
@@ -110,12 +244,140 @@ func parseTag(tag reflect.StructTag) (pt *parsedTag, err error) {
 			case labelKey:
 				pt.source = label
 				pt.value = keyvals[1]
+			case ownerKey:
+				pt.source = owner
+				pt.value = keyvals[1]
+			case labelPresenceKey:
+				pt.source = labelPresence
+				pt.value = keyvals[1]
+			case providerKey:
+				name, key, ok := strings.Cut(keyvals[1], providerSeparator)
+				if !ok || name == "" || key == "" {
+					return nil, fmt.Errorf("invalid src value. Expected <provider>=<key>, got '%s'", keyvals[1])
+				}
+				pt.source = provided
+				pt.provider, pt.value = name, key
+			case prefixKey:
+				pt.prefix = keyvals[1]
+			case absentKey:
+				if pt.absent, err = strconv.ParseBool(keyvals[1]); err != nil {
+					return nil, fmt.Errorf("invalid absent value. Expected bool, got '%s': [%w]", keyvals[1], err)
+				}
+				absentSet = true
+			case maxBytesKey:
+				if pt.maxBytes, err = strconv.Atoi(keyvals[1]); err != nil || pt.maxBytes <= 0 {
+					return nil, fmt.Errorf("invalid maxbytes value. Expected positive integer, got '%s'", keyvals[1])
+				}
+			case defaultKey:
+				pt.defaultValue, pt.hasDefault = keyvals[1], true
+			case templateKey:
+				if _, err = templateFields(keyvals[1]); err != nil {
+					return nil, err
+				}
+				pt.template = keyvals[1]
 			case aliasesKey:
 				pt.aliases = strings.Split(keyvals[1], ";")
+			case percentIntKey:
+				if keyvals[1] != suffixKey {
+					return nil, fmt.Errorf("invalid percentint value. Expected 'suffix', got '%s'", keyvals[1])
+				}
+				percent = percentSuffixEnc
 			default:
 				return nil, fmt.Errorf("invalid tag syntax. Expected <option>:<value>, unknown option: '%s'", keyvals[0])
 			}
 		}
 	}
+	if absentSet && pt.source != labelPresence {
+		return nil, errors.New("invalid tag syntax. 'absent' can be used only with 'labelpresence'")
+	}
+	if pt.prefix != "" && !pt.inline {
+		return nil, errors.New("invalid tag syntax. 'prefix' can be used only with 'inline'")
+	}
+	if pt.dnsCheck != nil && pt.source != name && pt.source != namespace && pt.source != annotation && pt.source != label {
+		return nil, errors.New("invalid tag syntax. DNS validation can be used only with name, namespace, annotation or label")
+	}
+	if pt.maxBytes > 0 && pt.source != annotation && pt.source != label {
+		return nil, errors.New("invalid tag syntax. 'maxbytes' can be used only with 'annotation' or 'label'")
+	}
+	if pt.aliasFirst && len(pt.aliases) == 0 {
+		return nil, errors.New("invalid tag syntax. 'aliasfirst' can be used only with 'aliases'")
+	}
+	if pt.fold && ((pt.source != annotation && pt.source != label) || pt.enc != encoder(undefined)) {
+		return nil, errors.New("invalid tag syntax. 'fold' can be used only with 'annotation' or 'label' without encoding")
+	}
+	if pt.hasDefault && pt.source != annotation && pt.source != label {
+		return nil, errors.New("invalid tag syntax. 'default' can be used only with 'annotation' or 'label'")
+	}
+	if pt.template != "" && (pt.source != name || pt.dir != out) {
+		return nil, errors.New("invalid tag syntax. 'template' can be used only with 'name' and 'out', as composed name cannot be decoded")
+	}
+	if percent != encoder(undefined) {
+		if pt.enc != encoder(undefined) {
+			return nil, errors.New("invalid tag syntax. 'percentint' cannot be used together with 'enc'")
+		}
+		pt.enc = percent
+	}
 	return pt, nil
 }
+
+// omitsDefault checks if encoded value should be omitted, because it equals default value of field with 'omitempty'.
+// Other values of such field, including zero value, are encoded, as their absence would be decoded as the default.
+func (pt *parsedTag) omitsDefault(val string) bool {
+	return pt.omitempty && pt.hasDefault && val == pt.defaultValue
+}
+
+// withPrefix returns tag with prefix prepended to all annotation and label keys.
+func (pt *parsedTag) withPrefix(prefix string) *parsedTag {
+	if prefix == "" {
+		return pt
+	}
+	return pt.withKeyFunc(func(_ source, key string) string { return prefix + key })
+}
+
+// lookupKeys returns key and aliases in order of precedence used during decoding.
+func (pt *parsedTag) lookupKeys() []string {
+	if pt.aliasFirst {
+		return append(slices.Clone(pt.aliases), pt.value)
+	}
+	return append([]string{pt.value}, pt.aliases...)
+}
+
+// withKeyFunc returns tag with all annotation and label keys transformed by fn. fn is called with source of map
+// the key refers to (annotation or label).
+func (pt *parsedTag) withKeyFunc(fn func(src source, key string) string) *parsedTag {
+	cp := *pt
+	switch pt.source {
+	case annotation, label:
+		cp.value = fn(pt.source, pt.value)
+	case labelPresence:
+		cp.value = fn(label, pt.value)
+	}
+	cp.aliases = make([]string, len(pt.aliases))
+	for i, alias := range pt.aliases {
+		cp.aliases[i] = fn(pt.source, alias)
+	}
+	cp.fallbacks = make([]keyRef, len(pt.fallbacks))
+	for i, ref := range pt.fallbacks {
+		cp.fallbacks[i] = keyRef{ref.source, fn(ref.source, ref.value)}
+	}
+	return &cp
+}
+
+// checkDNS returns an error when value is not empty and is not valid DNS-1123 name required by tag.
+func (pt *parsedTag) checkDNS(value string) error {
+	if pt.dnsCheck == nil || value == "" {
+		return nil
+	}
+	if errs := pt.dnsCheck(value); len(errs) > 0 {
+		return fmt.Errorf("invalid DNS-1123 value '%s': %s", value, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// checkSize returns an error when encoded value exceeds limit defined by 'maxbytes'.
+func (pt *parsedTag) checkSize(value string) error {
+	if pt.maxBytes > 0 && len(value) > pt.maxBytes {
+		return fmt.Errorf("encoded value has %d bytes, exceeds limit of %d bytes", len(value), pt.maxBytes)
+	}
+	return nil
+}
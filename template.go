@@ -0,0 +1,116 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// templateSegment is a part of template, which is either literal text or path of field referenced by placeholder.
+type templateSegment struct {
+	text  string
+	field bool
+}
+
+// templateFields splits template into literal text and paths of fields referenced as {<field path>} placeholders.
+func templateFields(tmpl string) ([]templateSegment, error) {
+	var segments []templateSegment
+	for rest := tmpl; rest != ""; {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			segments = append(segments, templateSegment{text: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("unexpected '}' in template '%s'", tmpl)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			return nil, fmt.Errorf("unterminated placeholder in template '%s'", tmpl)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("empty placeholder in template '%s'", tmpl)
+		}
+		if open > 0 {
+			segments = append(segments, templateSegment{text: rest[:open]})
+		}
+		segments = append(segments, templateSegment{text: rest[open+1 : open+1+end], field: true})
+		rest = rest[open+end+2:]
+	}
+	return segments, nil
+}
+
+// expandTemplate replaces placeholders of tmpl with encoded values of referenced tagged fields of encoded struct.
+// Values are encoded with encodings of referenced fields. Output is built in single pass, so placeholders contained in
+// values are not expanded.
+func expandTemplate(ec *encodeContext, tmpl string) (string, error) {
+	segments, err := templateFields(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, segment := range segments {
+		if !segment.field {
+			out.WriteString(segment.text)
+			continue
+		}
+		path := segment.text
+		info := ec.templateField(path)
+		if info == nil {
+			return "", fmt.Errorf("template references unknown tagged field '%s'", path)
+		}
+		if info.tag.inline || info.tag.enc == custom || info.tag.template != "" {
+			return "", fmt.Errorf("template cannot reference inline, custom or templated field '%s'", path)
+		}
+		v, ok := fieldByPath(ec.root, info.path)
+		if !ok {
+			return "", fmt.Errorf("field '%s' referenced by template is within nil inline struct", path)
+		}
+		val, err := encode(ec, v, &info.tag)
+		if err != nil {
+			return "", fmt.Errorf("cannot encode field '%s' referenced by template: [%w]", path, err)
+		}
+		out.WriteString(val)
+	}
+	return out.String(), nil
+}
+
+// templateField returns cached tagged field with given path or nil.
+func (ec *encodeContext) templateField(path string) *fieldInfo {
+	for i := range ec.cache.Fields {
+		if fieldName(ec.cache.CachedType, ec.cache.Fields[i].path) == path {
+			return &ec.cache.Fields[i]
+		}
+	}
+	return nil
+}
+
+// fieldByPath returns field of v at path. It returns false if any pointer on the path is nil.
+func fieldByPath(v reflect.Value, path []int) (reflect.Value, bool) {
+	for _, i := range path {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
@@ -0,0 +1,79 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Name template", func() {
+	type Inner struct {
+		Index int `k8s:"label:index"`
+	}
+	type S struct {
+		Name   string `k8s:"name,out,template:{Prefix}-{Suffix}-{Inner.Index}"`
+		Prefix string `k8s:"annotation:prefix"`
+		Suffix string `k8s:"label:suffix,in"`
+		Inner  *Inner `k8s:"inline"`
+	}
+
+	It("should compose name from referenced fields", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&S{Name: "ignored", Prefix: "web", Suffix: "canary", Inner: &Inner{Index: 2}}, m)).To(Succeed())
+		Expect(m.Name).To(Equal("web-canary-2"))
+		Expect(m.Annotations).To(HaveKeyWithValue("prefix", "web"))
+	})
+	It("should not decode name", func() {
+		s := S{}
+		Expect(Unmarshal(&metav1.ObjectMeta{Name: "web-canary-2"}, &s)).To(Succeed())
+		Expect(s.Name).To(BeEmpty())
+	})
+	It("should return an error when referenced field is within nil inline struct", func() {
+		err := Marshal(&S{Prefix: "web"}, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("field 'Inner.Index' referenced by template is within nil inline struct")))
+	})
+	It("should not expand placeholders contained in values", func() {
+		m := &metav1.ObjectMeta{}
+		Expect(Marshal(&struct {
+			Name string `k8s:"name,out,template:{A}-{B}"`
+			A    string `k8s:"annotation:a"`
+			B    string `k8s:"annotation:b"`
+		}{A: "{B}", B: "x"}, m)).To(Succeed())
+		Expect(m.Name).To(Equal("{B}-x"))
+	})
+	It("should return an error for unknown field", func() {
+		err := Marshal(&struct {
+			Name string `k8s:"name,out,template:{Missing}"`
+		}{}, &metav1.ObjectMeta{})
+		Expect(err).To(MatchError(ContainSubstring("template references unknown tagged field 'Missing'")))
+	})
+	DescribeTable("should reject invalid tags",
+		func(tag string, msg string) {
+			_, err := ParseStructTag(reflect.StructTag(tag))
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+		Entry("with inout direction", `k8s:"name,template:{A}"`, "'template' can be used only with 'name' and 'out'"),
+		Entry("with annotation", `k8s:"annotation:a,out,template:{A}"`, "'template' can be used only with 'name' and 'out'"),
+		Entry("with unterminated placeholder", `k8s:"name,out,template:{A"`, "unterminated placeholder"),
+		Entry("with empty placeholder", `k8s:"name,out,template:{}-x"`, "empty placeholder"),
+		Entry("with unexpected brace", `k8s:"name,out,template:A}"`, "unexpected '}'"),
+	)
+})
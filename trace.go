@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"reflect"
+	"strings"
+)
+
+// TraceEvent describes resolution of single struct field during decoding.
+type TraceEvent struct {
+	// Field is a dot separated path of struct field names.
+	Field string
+	// Source is the metadata source of the field (e.g. 'annotation', 'label', 'name').
+	Source string
+	// Key is the matched annotation or label key. It may be an alias or fallback key.
+	Key string
+	// Present is false when the key was not found in metadata.
+	Present bool
+	// Raw is the raw metadata value.
+	Raw string
+	// Encoding is the name of encoding scheme used for the field.
+	Encoding string
+	// Value is the decoded field value. It is nil when decoding failed.
+	Value any
+	// Err is the decoding error.
+	Err error
+}
+
+// Trace enforces decoder to append TraceEvent for every decoded field into 'into'.
+func Trace(into *[]TraceEvent) DecodeOption {
+	return func(dec *decodeContext) {
+		dec.trace = into
+	}
+}
+
+func traceField(dc *decodeContext, info *fieldInfo, v reflect.Value, err error) {
+	event := TraceEvent{
+		Field:    fieldName(dc.root.Type(), info.path),
+		Source:   info.tag.source.String(),
+		Key:      info.tag.value,
+		Present:  true,
+		Encoding: info.tag.enc.String(),
+		Err:      err,
+	}
+	switch info.tag.source {
+	case name:
+		event.Raw = dc.meta.GetName()
+	case namespace:
+		event.Raw = dc.meta.GetNamespace()
+	case annotation, label:
+		var ref keyRef
+		ref, event.Raw, event.Present = resolve(dc.meta, &info.tag)
+		event.Source, event.Key = ref.source.String(), ref.value
+	case labelPresence:
+		event.Raw, event.Present = dc.meta.GetLabels()[info.tag.value]
+	case source(undefined):
+		event.Source = customKey
+	}
+	if err == nil && v.CanInterface() {
+		event.Value = v.Interface()
+	}
+	*dc.trace = append(*dc.trace, event)
+}
+
+func fieldName(t reflect.Type, path []int) string {
+	names := make([]string, 0, len(path))
+	for _, i := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		f := t.Field(i)
+		names = append(names, f.Name)
+		t = f.Type
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,60 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Decoder with Trace enabled", func() {
+	It("should emit event for every processed field", func() {
+		type Inner struct {
+			C int `k8s:"annotation:c"`
+		}
+		s := struct {
+			Name  string `k8s:"name"`
+			A     int    `k8s:"annotation:a,aliases:old-a"`
+			B     string `k8s:"annotation:b|label:b"`
+			Bad   int    `k8s:"label:bad,enc:json"`
+			Inner *Inner `k8s:"inline"`
+		}{}
+		m := &metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: map[string]string{"old-a": "1", "c": "3"},
+			Labels:      map[string]string{"b": "two", "bad": "x"},
+		}
+		var events []TraceEvent
+		err := Unmarshal(m, &s, Trace(&events), AccumulateFieldErrors())
+		Expect(err).To(HaveOccurred())
+		Expect(events).To(ConsistOf(
+			TraceEvent{Field: "Name", Source: "name", Key: "", Present: true, Raw: "test", Encoding: "default", Value: "test"},
+			TraceEvent{Field: "A", Source: "annotation", Key: "old-a", Present: true, Raw: "1", Encoding: "default", Value: 1},
+			TraceEvent{Field: "B", Source: "label", Key: "b", Present: true, Raw: "two", Encoding: "default", Value: "two"},
+			HaveField("Field", "Bad"),
+			TraceEvent{Field: "Inner.C", Source: "annotation", Key: "c", Present: true, Raw: "3", Encoding: "default", Value: 3},
+		))
+		for _, e := range events {
+			if e.Field == "Bad" {
+				Expect(e.Err).To(HaveOccurred())
+				Expect(e.Value).To(BeNil())
+				Expect(e.Encoding).To(Equal("json"))
+			}
+		}
+	})
+})
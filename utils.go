@@ -17,10 +17,33 @@ limitations under the License.
 package metaser
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	bigIntType       = reflect.TypeOf(big.Int{})
+	rawJsonType      = reflect.TypeOf(json.RawMessage(nil))
+	metaTimeType     = reflect.TypeOf(metav1.Time{})
+	timeType         = reflect.TypeOf(time.Time{})
+	durationType     = reflect.TypeOf(time.Duration(0))
+	metaDurationType = reflect.TypeOf(metav1.Duration{})
+	objectMetaType   = reflect.TypeOf(metav1.ObjectMeta{})
+	typeMetaType     = reflect.TypeOf(metav1.TypeMeta{})
 )
 
+// isBytes checks if t is a slice or an array of bytes.
+func isBytes(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
 func dereference(v reflect.Value) reflect.Value {
 	if v.Kind() == reflect.Pointer {
 		return v.Elem()
@@ -41,49 +64,131 @@ func implements[T any](out reflect.Value) bool {
 		(out.CanAddr() && out.Addr().Type().Implements(reflect.TypeOf((*T)(nil)).Elem()))
 }
 
-type tp struct {
-	t reflect.Type
-	p []int
+// checkStructElem returns an error when elements of slice or array type t are structs, which cannot be serialized
+// with default encoding scheme unless they implement encoding.TextMarshaler or encoding.TextUnmarshaler or a codec
+// for them is registered in codecs.
+func checkStructElem[C any](t reflect.Type, codecs map[reflect.Type]C) error {
+	elem := t.Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct || elem == metaDurationType || strings.HasPrefix(elem.String(), "metaser.Option") {
+		return nil
+	}
+	if _, ok := codecs[elem]; ok {
+		return nil
+	}
+	ptr := reflect.PointerTo(elem)
+	if ptr.Implements(textMarshalerType) || ptr.Implements(textUnmarshalerType) {
+		return nil
+	}
+	return fmt.Errorf("elements of struct type '%s' cannot be serialized with default encoding, use 'enc:json' tag: [%w]",
+		elem, ErrUnsupportedType)
 }
 
-func visit(root reflect.Type, visitor func(reflect.Type, []int) (bool, error)) error {
-	q := []tp{{root, nil}}
-	visited := map[reflect.Type]struct{}{}
-
-	for l := len(q); l > 0; l = len(q) {
-		e := q[l-1]
-		q = q[:l-1]
-		if _, ok := visited[e.t]; ok {
-			continue
-		}
-		visited[e.t] = struct{}{}
-		expand, err := visitor(e.t, e.p)
-		if err != nil {
-			return err
-		}
-		if !expand {
-			continue
-		}
-		if e.t.Kind() == reflect.Struct {
-			for i := 0; i < e.t.NumField(); i++ {
-				p := make([]int, len(e.p)+1)
-				copy(p, append(e.p, i))
-				q = append(q, tp{e.t.Field(i).Type, p})
-			}
-		}
-		if e.t.Kind() == reflect.Pointer {
-			q = append(q, tp{e.t.Elem(), e.p})
+// valueType returns type of value held by t through pointers and options.
+func valueType(t reflect.Type) reflect.Type {
+	for {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		} else if t.Kind() == reflect.Struct && strings.HasPrefix(t.String(), "metaser.Option") {
+			t = t.Field(valueFieldIndex).Type
+		} else {
+			return t
 		}
 	}
-	return nil
 }
 
 func isOption(out reflect.Value) bool {
 	return strings.HasPrefix(out.Type().String(), "metaser.Option")
 }
 
-// asWritableValue constructs new writable reflact.Value from none readable/writable value.
-// if 'v' is not addressable, function will panic.
+// errUnsetOptionElem is returned when unset Option is an element of collection with default encoding. Unset option
+// would be serialized as empty element, which decodes as set option (or fails to decode), so it cannot round-trip.
+var errUnsetOptionElem = errors.New("unset Option cannot be an element of collection with default encoding")
+
+// asWritableValue constructs new writable reflect.Value from none readable/writable value, e.g. unexported field
+// of Option. It points to the same memory as v, so writes are visible through v. If 'v' is not addressable, function
+// will panic, so callers copy non-addressable values first (see optionValue).
 func asWritableValue(v reflect.Value) reflect.Value {
 	return reflect.NewAt(v.Type(), v.Addr().UnsafePointer()).Elem()
 }
+
+// checkPercent validates if integer value is within [0, 100] range. Nil pointers and unset options are accepted.
+func checkPercent(v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || isOption(v) {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		} else {
+			if !v.Field(isSetFieldIndex).Bool() {
+				return nil
+			}
+			v = v.Field(valueFieldIndex)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 || v.Int() > 100 {
+			return fmt.Errorf("value %d is out of percentage range [0, 100]", v.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > 100 {
+			return fmt.Errorf("value %d is out of percentage range [0, 100]", v.Uint())
+		}
+	default:
+		return fmt.Errorf("percentint requires integer type, got '%s'", v.Type())
+	}
+	return nil
+}
+
+// deepCopy copies src into dst allocating new pointers, slices and maps. dst must be settable.
+func deepCopy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		n := reflect.New(src.Type().Elem())
+		deepCopy(n.Elem(), src.Elem())
+		dst.Set(n)
+	case reflect.Struct:
+		if !src.CanAddr() {
+			tmp := reflect.New(src.Type()).Elem()
+			tmp.Set(src)
+			src = tmp
+		}
+		for i := 0; i < src.NumField(); i++ {
+			deepCopy(asWritableValue(dst.Field(i)), asWritableValue(src.Field(i)))
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		n := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(n.Index(i), src.Index(i))
+		}
+		dst.Set(n)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		n := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			v := reflect.New(src.Type().Elem()).Elem()
+			deepCopy(v, iter.Value())
+			n.SetMapIndex(iter.Key(), v)
+		}
+		dst.Set(n)
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var stringMapType = reflect.TypeOf(map[string]string(nil))
+
+// decodeWholeMap copies all labels or annotations into map[string]string field.
+func decodeWholeMap(dc *decodeContext, out reflect.Value, values map[string]string) error {
+	if out.Type() != stringMapType {
+		return fmt.Errorf("labels and annotations require map[string]string type, got '%s'", out.Type())
+	}
+	if !dc.mergeCollections || out.IsNil() {
+		out.Set(reflect.MakeMapWithSize(stringMapType, len(values)))
+	}
+	for k, v := range values {
+		out.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// encodeWholeMaps writes content of fields tagged with 'labels' or 'annotations' into metadata. Keys written
+// by other fields are never overwritten. With ReplaceManagedMaps option all other keys are removed.
+func encodeWholeMaps(ec *encodeContext) error {
+	for _, dv := range ec.wholeMaps {
+		if dv.value.Type() != stringMapType {
+			return fmt.Errorf("labels and annotations require map[string]string type, got '%s'", dv.value.Type())
+		}
+		out, written := ec.out.Labels, ec.written.Labels
+		if dv.tag.source == allAnnotations {
+			out, written = ec.out.Annotations, ec.written.Annotations
+		}
+		if ec.replaceMaps {
+			for k := range out {
+				if _, ok := written[k]; !ok && !dv.value.MapIndex(reflect.ValueOf(k)).IsValid() {
+					delete(out, k)
+				}
+			}
+		}
+		iter := dv.value.MapRange()
+		for iter.Next() {
+			if _, ok := written[iter.Key().String()]; !ok {
+				out[iter.Key().String()] = iter.Value().String()
+			}
+		}
+	}
+	return nil
+}
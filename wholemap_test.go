@@ -0,0 +1,62 @@
+/*
+Copyright (c) 2026 Samsung Electronics Co., Ltd All Rights Reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metaser
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Whole map fields", func() {
+	type S struct {
+		Labels      map[string]string `k8s:"labels"`
+		Annotations map[string]string `k8s:"annotations,omitempty"`
+		App         string            `k8s:"label:app"`
+	}
+
+	It("should decode all labels and annotations", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": "front"}, Annotations: map[string]string{"a": "1"}}
+		s := S{}
+		Expect(Unmarshal(m, &s)).To(Succeed())
+		Expect(s).To(Equal(S{Labels: m.Labels, Annotations: m.Annotations, App: "web"}))
+		s.Labels["x"] = "y"
+		Expect(m.Labels).ToNot(HaveKey("x"))
+	})
+	It("should merge maps by default with per-key fields taking precedence", func() {
+		m := &metav1.ObjectMeta{Labels: map[string]string{"old": "1", "tier": "back"}}
+		Expect(Marshal(&S{Labels: map[string]string{"tier": "front", "app": "ignored"}, App: "web"}, m)).To(Succeed())
+		Expect(m.Labels).To(Equal(map[string]string{"old": "1", "tier": "front", "app": "web"}))
+	})
+	It("should replace maps with ReplaceManagedMaps", func() {
+		m := &metav1.ObjectMeta{
+			Labels:      map[string]string{"old": "1", "tier": "back"},
+			Annotations: map[string]string{"keep": "1"},
+		}
+		Expect(Marshal(&S{Labels: map[string]string{"tier": "front", "app": "ignored"}, App: "web"}, m, ReplaceManagedMaps())).To(Succeed())
+		Expect(m.Labels).To(Equal(map[string]string{"tier": "front", "app": "web"}))
+		Expect(m.Annotations).To(Equal(map[string]string{"keep": "1"}))
+	})
+	It("should return error for unsupported type", func() {
+		Expect(Unmarshal(&metav1.ObjectMeta{}, &struct {
+			V map[string]int `k8s:"labels"`
+		}{})).To(HaveOccurred())
+		Expect(Marshal(&struct {
+			V map[string]int `k8s:"annotations"`
+		}{}, &metav1.ObjectMeta{})).To(HaveOccurred())
+	})
+})